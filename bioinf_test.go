@@ -1,7 +1,14 @@
 package bioinf
 
 import (
+	"bytes"
+	"compress/gzip"
 	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -19,3 +26,540 @@ func TestConversion(t *testing.T) {
 	}
 	math.Abs(2)
 }
+
+func TestUnknown(t *testing.T) {
+	seq := AToSeq("AN-R.gn")
+	expected := []Base{A, N, X, N, X, G, N}
+	for i, b := range seq.Bases {
+		if b != expected[i] {
+			t.Errorf("position %v: got %v, expected %v", i, b, expected[i])
+		}
+	}
+	cfg := DefaultScoreConfig()
+	if cfg.PairScore(N, A) != 0 || cfg.PairScore(N, N) != 0 || cfg.PairScore(N, X) != int64(gap) {
+		t.Error("Incorrect pair score.")
+	}
+	cfg.Unknown = -1
+	if s := ColumnScore([]Base{A, N, X}, cfg); s != int64(-1+2*gap) {
+		t.Errorf("got %v", s)
+	}
+}
+
+func TestValid(t *testing.T) {
+	seq := AToSeq("AC-GT")
+	if err := seq.Valid(DNA); err != nil {
+		t.Error(err)
+	}
+	seq.Bases = append(seq.Bases, Base(42))
+	if err := seq.Valid(DNA); err == nil {
+		t.Error("expected out of range base to be rejected")
+	}
+}
+
+func TestParseCaseAndWhitespace(t *testing.T) {
+	want := AToSeq("ACGTN")
+	if got := AToSeq(" aC\tg\r\nTn\n"); !slices.Equal(got.Bases, want.Bases) {
+		t.Errorf("got %v", got.Bases)
+	}
+	MaskLowerCase = true
+	defer func() { MaskLowerCase = false }()
+	if got := AToSeq("aCgT"); !slices.Equal(got.Bases, []Base{N, C, N, T}) {
+		t.Errorf("masked: got %v", got.Bases)
+	}
+	if got := AToProtein("Mk W"); !slices.Equal(got.Bases, []Base{Residue('M'), N, Residue('W')}) {
+		t.Errorf("masked protein: got %v", got.Bases)
+	}
+}
+
+func TestIUPAC(t *testing.T) {
+	seq := AToIUPAC("ArYn-")
+	if err := seq.Valid(IUPAC); err != nil {
+		t.Error(err)
+	}
+	if err := seq.Valid(DNA); err == nil {
+		t.Error("expected ambiguity codes to be rejected as DNA")
+	}
+	r, y, n := IUPACBase('R'), IUPACBase('Y'), IUPACBase('N')
+	if seq.Bases[0] != A || seq.Bases[1] != r || seq.Bases[3] != n || n == N {
+		t.Errorf("got %v", seq.Bases)
+	}
+	if !slices.Equal(r.Nucleotides(), []Base{A, G}) || !slices.Equal(n.Nucleotides(), ConcreteBases()) ||
+		!slices.Equal(C.Nucleotides(), []Base{C}) || N.Nucleotides() != nil {
+		t.Errorf("Nucleotides: got %v %v %v %v", r.Nucleotides(), n.Nucleotides(), C.Nucleotides(), N.Nucleotides())
+	}
+	if AmbiguityCode(r, y) != n || AmbiguityCode(A, G, X) != r || AmbiguityCode(T) != T || AmbiguityCode(N) != X {
+		t.Error("Incorrect ambiguity codes.")
+	}
+	cfg := DefaultScoreConfig()
+	for _, c := range []struct {
+		b1, b2 Base
+		want   int
+	}{
+		{r, A, match}, {A, r, match}, {r, C, mismatch}, {r, y, mismatch},
+		{r, IUPACBase('S'), match}, {n, T, match}, {n, y, match}, {r, X, gap}, {r, N, 0},
+	} {
+		if s := cfg.PairScore(c.b1, c.b2); s != int64(c.want) {
+			t.Errorf("PairScore(%v, %v) = %v, want %v", c.b1, c.b2, s, c.want)
+		}
+	}
+	// the strict parser keeps scoring ambiguity codes as unknown
+	if b := AToSeq("R").Bases[0]; b != N {
+		t.Errorf("got %v", b)
+	}
+}
+
+func TestParseBase(t *testing.T) {
+	for r, want := range map[rune]Base{'A': A, 'c': C, 'G': G, 't': T, 'n': N, '-': X, '.': X} {
+		if b, err := ParseBase(r); err != nil || b != want {
+			t.Errorf("ParseBase(%q) = %v, %v", r, b, err)
+		}
+	}
+	for _, r := range " RX*" {
+		if _, err := ParseBase(r); err == nil {
+			t.Errorf("expected %q to be rejected", r)
+		}
+	}
+	if s := AToSeq("ac-GTN").String(); s != "AC-GTN" {
+		t.Errorf("got %v", s)
+	}
+	if s := AToProtein("MKw").String() + AToIUPAC("RyN").String(); s != "MKWRYN" {
+		t.Errorf("got %v", s)
+	}
+	if s := X.String() + Base(99).String(); s != "-Base(99)" {
+		t.Errorf("got %v", s)
+	}
+}
+
+func TestSequenceBuilder(t *testing.T) {
+	s := NewSequence()
+	s.AppendBase(A)
+	s.AppendBase(X)
+	if s.Len() != 2 || s.String() != "A-" {
+		t.Errorf("got %v", s)
+	}
+	s, err := SequenceFromString("ac gT\n")
+	if err != nil || s.String() != "ACGT" {
+		t.Errorf("got %v, %v", s, err)
+	}
+	if _, err := SequenceFromString("ACRT"); err == nil || !strings.Contains(err.Error(), "position 2") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestRNA(t *testing.T) {
+	seq := AToSeq("ACGUu")
+	if err := seq.Valid(RNA); err != nil {
+		t.Error(err)
+	}
+	if !slices.Equal(seq.Bases, AToSeq("ACGTT").Bases) || seq.RNAString() != "ACGUU" {
+		t.Errorf("got %v", seq)
+	}
+	if b, err := ParseBase('U'); err != nil || b != T {
+		t.Errorf("got %v, %v", b, err)
+	}
+	if s := PairScore(AToBase("U"), T); s != int64(match) {
+		t.Errorf("got %v", s)
+	}
+}
+
+func TestProtein(t *testing.T) {
+	seq := AToProtein("MkWvx-")
+	if err := seq.Valid(Protein); err != nil {
+		t.Error(err)
+	}
+	if seq.Bases[1] != Residue('K') || seq.Bases[4] != N || seq.Bases[5] != X {
+		t.Errorf("got %v", seq.Bases)
+	}
+	if err := AToSeq("ACGT").Valid(Protein); err == nil {
+		t.Error("expected nucleotides to be rejected")
+	}
+	cfg := DefaultScoreConfig()
+	cfg.SetMatch(A, 10)
+	// alanine is not the nucleotide A
+	if s := cfg.PairScore(Residue('A'), Residue('A')); s != int64(match) {
+		t.Errorf("got %v", s)
+	}
+	if s := ColumnScore([]Base{Residue('W'), Residue('W'), Residue('Y'), X}, cfg); s != int64(match+2*mismatch+3*gap) {
+		t.Errorf("got %v", s)
+	}
+}
+
+func TestReadMatrix(t *testing.T) {
+	in := "#  a fragment of BLOSUM62\n   A  R  W  X  *\nA  4 -1 -3  0 -4\nR -1  5 -3 -1 -4\nW -3 -3 11 -2 -4\nX  0 -1 -2 -1 -4\n* -4 -4 -4 -4  1\n"
+	score, err := ReadMatrix(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, r, w := Residue('A'), Residue('R'), Residue('W')
+	if score(a, a) != 8 || score(r, w) != -6 || score(w, r) != -6 || score(w, N) != -4 || score(a, Residue('K')) != 0 {
+		t.Error("Incorrect score.")
+	}
+	if _, err := ReadMatrix(strings.NewReader("   A  R\nA  4 -1\nR  0  5\n")); err == nil {
+		t.Error("expected asymmetric matrix to be rejected")
+	}
+	if _, err := ReadMatrix(strings.NewReader("   A  R\nA  4\n")); err == nil {
+		t.Error("expected short row to be rejected")
+	}
+}
+
+func TestReadFASTA(t *testing.T) {
+	in := ">seq1 first record\r\nACGT\r\nAC\r\n\r\n>seq2\nGG\n\n>empty\n"
+	records, err := ReadFASTA(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FASTARecord{{"seq1", "ACGTAC"}, {"seq2", "GG"}, {"empty", ""}}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %v records, got %v", len(expected), len(records))
+	}
+	for i, r := range records {
+		if r != expected[i] {
+			t.Errorf("record %v: got %v, expected %v", i, r, expected[i])
+		}
+	}
+	if _, err := ReadFASTA(strings.NewReader("ACGT\n>seq1\nA\n")); err == nil {
+		t.Error("expected error for sequence before header")
+	}
+}
+
+func TestReadFASTAGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(">seq1\nACGT\nAC\n>seq2\nGG\n"))
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "seqs.fasta.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadFASTAFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Seq != "ACGTAC" || records[1].Seq != "GG" {
+		t.Errorf("got %v", records)
+	}
+	if _, err := ReadFASTA(bytes.NewReader(buf.Bytes()[:4])); err == nil {
+		t.Error("expected error for truncated gzip input")
+	}
+}
+
+func FuzzReadFASTA(f *testing.F) {
+	f.Add(">seq1 first record\r\nACGT\r\nAC\r\n\r\n>seq2\nGG\n\n>empty\n")
+	f.Add(">\nACGT\n")
+	f.Add("ACGT\n>seq1\nA\n")
+	f.Add(">a\n>b\n\r\n\t \n")
+	f.Fuzz(func(t *testing.T, in string) {
+		records, err := ReadFASTA(strings.NewReader(in))
+		if err != nil {
+			return
+		}
+		total := 0
+		for i, r := range records {
+			if r.ID == "" || strings.ContainsAny(r.ID, " \t\r\n") {
+				t.Errorf("record %v: bad id %q", i, r.ID)
+			}
+			if strings.ContainsRune(r.Seq, '\n') {
+				t.Errorf("record %v: sequence contains a newline", i)
+			}
+			total += len(r.ID) + len(r.Seq)
+		}
+		if total > len(in) {
+			t.Errorf("records hold %v bytes from %v bytes of input", total, len(in))
+		}
+	})
+}
+
+func TestGapModel(t *testing.T) {
+	col := []Base{A, A, X, X}
+	cfg := DefaultScoreConfig()
+	if s := cfg.ColumnSPScore(col); s != -6 {
+		t.Errorf("pairwise: got %v", s)
+	}
+	cfg.GapModel = Linear
+	if s := cfg.ColumnSPScore(col); s != 0 {
+		t.Errorf("linear: got %v", s)
+	}
+	if s := cfg.ColumnSPScore([]Base{X, X}); s != 0 {
+		t.Errorf("all-gap column: got %v", s)
+	}
+	pair := []Base{C, X}
+	if cfg.ColumnSPScore(pair) != DefaultScoreConfig().ColumnSPScore(pair) {
+		t.Error("models should agree on two sequences")
+	}
+}
+
+func TestColumnPairScores(t *testing.T) {
+	col := []Base{A, A, C, X}
+	cfg := DefaultScoreConfig()
+	scores := ColumnPairScores(col, cfg)
+	t.Log(scores)
+	sum := int64(0)
+	for i := range scores {
+		for j := i + 1; j < len(scores); j++ {
+			sum += scores[i][j]
+		}
+	}
+	if sum != ColumnScore(col, cfg) || scores[0][1] != int64(match) || scores[2][0] != int64(mismatch) || scores[3][3] != 0 {
+		t.Error("Incorrect pair scores.")
+	}
+	cfg.GapModel = Linear
+	if scores := ColumnPairScores(col, cfg); scores[0][3] != 0 || scores[0][2] != int64(mismatch) {
+		t.Errorf("linear: got %v", scores)
+	}
+}
+
+func TestColumnScoreFromCounts(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cfg := DefaultScoreConfig()
+	cfg.Unknown, cfg.ColumnGap = -1, -5
+	cfg.SetMatch(G, 2)
+	forbid := cfg
+	forbid.Forbid(A, T)
+	for n := 0; n < 500; n++ {
+		col := make([]Base, 1+r.Intn(12))
+		counts := map[Base]int{}
+		for i := range col {
+			col[i] = []Base{A, C, G, T, X, N}[r.Intn(6)]
+			counts[col[i]]++
+		}
+		for _, c := range []ScoreConfig{cfg, forbid} {
+			for _, model := range []GapModel{Pairwise, Linear, PerColumn} {
+				c.GapModel = model
+				if got, expected := ColumnScoreFromCounts(counts, c), ColumnScore(col, c); got != expected {
+					t.Fatalf("%v under %v: got %v, expected %v", col, model, got, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestPerColumnGap(t *testing.T) {
+	cfg := DefaultScoreConfig()
+	cfg.GapModel = PerColumn
+	cfg.ColumnGap = -5
+	if s := ColumnScore([]Base{A, A, X, X}, cfg); s != int64(match-5) {
+		t.Errorf("got %v", s)
+	}
+	if s := ColumnScore([]Base{A, C, X}, cfg); s != int64(mismatch-5) {
+		t.Errorf("got %v", s)
+	}
+	if s := ColumnScore([]Base{A, A}, cfg); s != int64(match) {
+		t.Errorf("ungapped: got %v", s)
+	}
+	if s := ColumnScore([]Base{X, X}, cfg); s != 0 {
+		t.Errorf("all-gap column: got %v", s)
+	}
+}
+
+func TestColumnScore(t *testing.T) {
+	col := []Base{A, A, C, X}
+	// A-A match, two A-C mismatches, three base-gap pairs
+	if s := ColumnScore(col, DefaultScoreConfig()); s != 6-4-4-3-3-3 {
+		t.Errorf("got %v", s)
+	}
+	if ColumnScore(col, DefaultScoreConfig()) != ColumnSPScore(col) {
+		t.Error("ColumnScore and ColumnSPScore disagree")
+	}
+	if s := ColumnScore([]Base{G}, DefaultScoreConfig()); s != 0 {
+		t.Errorf("single base column: got %v", s)
+	}
+}
+
+func TestScoreScales(t *testing.T) {
+	if ToNatural(-3) != -1.5 {
+		t.Error("ToNatural(-3) != -1.5")
+	}
+	for _, x := range []float64{0, 3, -1.5, 22.5} {
+		d, err := ToDoubled(x)
+		if err != nil || ToNatural(int64(d)) != x {
+			t.Errorf("%v did not round trip: %v %v", x, d, err)
+		}
+	}
+	if _, err := ToDoubled(0.25); err == nil {
+		t.Error("expected 0.25 to be rejected")
+	}
+	if _, err := ToDoubled(math.Inf(1)); err == nil {
+		t.Error("expected infinity to be rejected")
+	}
+}
+
+func TestForbid(t *testing.T) {
+	cfg := DefaultScoreConfig()
+	cfg.Forbid(A, G)
+	if cfg.PairScore(G, A) != Forbidden || cfg.PairScore(A, C) != int64(mismatch) {
+		t.Error("Incorrect pair score.")
+	}
+	if s := ColumnScore([]Base{A, X, G, G}, cfg); s != Forbidden {
+		t.Errorf("got %v", s)
+	}
+	cfg.GapModel = Linear
+	if s := ColumnScore([]Base{A, X, G}, cfg); s != Forbidden {
+		t.Errorf("linear: got %v", s)
+	}
+	if !math.IsInf(ToNatural(Forbidden), -1) {
+		t.Error("Forbidden should convert to -Inf.")
+	}
+	if AddScores(Forbidden, -1) != Forbidden || AddScores(2, 3) != 5 {
+		t.Error("Incorrect sum.")
+	}
+	if MaxScore() != Forbidden || MaxScore(Forbidden, AddScores(Forbidden, -5)) != Forbidden ||
+		MaxScore(Forbidden, -3, -7) != -3 {
+		t.Error("Incorrect max.")
+	}
+}
+
+func TestSetMatch(t *testing.T) {
+	cfg := DefaultScoreConfig()
+	cfg.SetMatch(A, 8)
+	cfg.SetMatch(T, 0)
+	if cfg.PairScore(A, A) != 8 || cfg.PairScore(T, T) != 0 || cfg.PairScore(G, G) != int64(match) {
+		t.Error("Incorrect match score.")
+	}
+	if cfg.PairScore(A, T) != int64(mismatch) || cfg.PairScore(N, N) != int64(cfg.Unknown) {
+		t.Error("Incorrect pair score.")
+	}
+	if cfg == DefaultScoreConfig() {
+		t.Error("expected configs to differ")
+	}
+}
+
+func TestConcreteBases(t *testing.T) {
+	bases := ConcreteBases()
+	if len(bases) != AlphabetSize {
+		t.Fatalf("got %v bases", len(bases))
+	}
+	for i, b := range bases {
+		if int(b) != i || b == X || b == N {
+			t.Errorf("base %v: got %v", i, b)
+		}
+	}
+}
+
+func TestPropertyScorer(t *testing.T) {
+	kd := KyteDoolittle()
+	if s, err := kd.PairScore('I', 'v'); err != nil || math.Abs(s+0.3) > 1e-9 {
+		t.Errorf("got %v (%v)", s, err)
+	}
+	iv, _ := kd.PairScore('I', 'V')
+	ir, _ := kd.PairScore('I', 'R')
+	if s, _ := kd.PairScore('L', 'L'); s != 0 || ir >= iv {
+		t.Error("Incorrect score.")
+	}
+	if _, err := kd.PairScore('I', '-'); err == nil {
+		t.Error("expected error for a residue without properties")
+	}
+	m := kd.Matrix(10)
+	if m(Residue('I'), Residue('V')) != -6 || m(Residue('L'), Residue('L')) != 0 || m(Residue('I'), N) != 0 {
+		t.Error("Incorrect matrix score.")
+	}
+}
+
+func TestReverseComplement(t *testing.T) {
+	rc := AToSeq("AAC-GTN").ReverseComplement()
+	expected := AToSeq("NAC-GTT")
+	for i, b := range rc.Bases {
+		if b != expected.Bases[i] {
+			t.Errorf("position %v: got %v, expected %v", i, b, expected.Bases[i])
+		}
+	}
+	for code, want := range map[rune]rune{'R': 'Y', 'K': 'M', 'S': 'S', 'B': 'V', 'N': 'N'} {
+		if got := IUPACBase(code).Complement(); got != IUPACBase(want) {
+			t.Errorf("%c: got %v, expected %c", code, got, want)
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	if cfg, ok := Preset("edit-distance"); !ok || cfg != EditDistanceConfig() {
+		t.Error("missing built-in preset")
+	}
+	cfg := DefaultScoreConfig()
+	cfg.Gap = -8
+	RegisterPreset("test-strict-gaps", cfg)
+	if got, err := LookupPreset("test-strict-gaps"); err != nil || got.Gap != -8 {
+		t.Errorf("got %v (%v)", got, err)
+	}
+	if cfg, ok := Preset("blosum62"); !ok || cfg != BLOSUM62Config() {
+		t.Error("missing blosum62 preset")
+	}
+	// W/W is the largest cell of the table and E/D a positive off-diagonal one
+	blosum := BLOSUM62()
+	w, e, d := Residue('W'), Residue('E'), Residue('D')
+	if blosum(w, w) != 22 || blosum(e, d) != 4 || blosum(d, e) != 4 || blosum(w, Residue('P')) != -8 {
+		t.Error("Incorrect BLOSUM62 score.")
+	}
+	_, err := LookupPreset("no-such-preset")
+	if err == nil || !strings.Contains(err.Error(), "edit-distance") {
+		t.Errorf("expected error listing presets, got %v", err)
+	}
+	t.Log(PresetNames())
+}
+
+func TestColumnScoreFewerThanTwo(t *testing.T) {
+	for _, model := range []GapModel{Pairwise, Linear, PerColumn} {
+		cfg := DefaultScoreConfig()
+		cfg.GapModel, cfg.ColumnGap = model, gap
+		for _, col := range [][]Base{nil, {A}, {X}} {
+			if s := ColumnScore(col, cfg); s != 0 {
+				t.Errorf("%v %v: got %v, expected 0", model, col, s)
+			}
+		}
+	}
+}
+
+func TestGapGap(t *testing.T) {
+	cfg := DefaultScoreConfig()
+	if cfg.PairScore(X, X) != 0 {
+		t.Error("Incorrect pair score.")
+	}
+	cfg.GapGap = -1
+	if cfg.PairScore(X, X) != -1 || cfg.PairScore(A, X) != int64(gap) {
+		t.Error("Incorrect pair score.")
+	}
+	if s := ColumnScore([]Base{A, X, X}, cfg); s != int64(2*gap-1) {
+		t.Errorf("got %v", s)
+	}
+	counts := map[Base]int{A: 1, X: 2}
+	if s := ColumnScoreFromCounts(counts, cfg); s != int64(2*gap-1) {
+		t.Errorf("counts: got %v", s)
+	}
+}
+
+func TestScoresSaturate(t *testing.T) {
+	for _, c := range []struct{ a, b, want int64 }{
+		// past the range of a 32-bit int, exact on every GOARCH
+		{math.MaxInt32, math.MaxInt32, 2 * math.MaxInt32},
+		{math.MinInt32, -1, math.MinInt32 - 1},
+		{math.MaxInt64 - 1, 5, math.MaxInt64},
+		{math.MaxInt64, math.MaxInt64, math.MaxInt64},
+		{Forbidden + 2, -5, Forbidden + 1},
+		{Forbidden + 1, Forbidden + 1, Forbidden + 1},
+		{math.MaxInt64, Forbidden + 1, 0},
+		{3, Forbidden, Forbidden},
+	} {
+		if got := AddScores(c.a, c.b); got != c.want {
+			t.Errorf("AddScores(%v, %v): got %v, expected %v", c.a, c.b, got, c.want)
+		}
+	}
+	for _, c := range []struct{ n, s, want int64 }{
+		{3, -4, -12},
+		{1 << 20, 1 << 20, 1 << 40},
+		{2, math.MaxInt64/2 + 1, math.MaxInt64},
+		{-2, math.MaxInt64/2 + 1, Forbidden + 1},
+		{2, math.MinInt64 / 2, Forbidden + 1},
+		{-1, Forbidden + 1, math.MaxInt64},
+		{0, Forbidden, 0},
+		{2, Forbidden, Forbidden},
+	} {
+		if got := MulScores(c.n, c.s); got != c.want {
+			t.Errorf("MulScores(%v, %v): got %v, expected %v", c.n, c.s, got, c.want)
+		}
+	}
+	// a column whose score overflows int32: 2^17 rows give 2^33 base pairs
+	n := int64(1 << 17)
+	counts := map[Base]int{A: int(n)}
+	want := n * (n - 1) / 2 * int64(match)
+	if got := ColumnScoreFromCounts(counts, DefaultScoreConfig()); got != want {
+		t.Errorf("got %v, expected %v", got, want)
+	}
+}
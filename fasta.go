@@ -0,0 +1,80 @@
+package bioinf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FASTARecord is a single FASTA entry
+type FASTARecord struct {
+	ID  string // first word of the header line, without '>'
+	Seq string // sequence lines joined together
+}
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadFASTA reads every record from r. wrapped sequence lines are joined,
+// blank lines and surrounding whitespace (including '\r') are ignored.
+// gzip-compressed input is detected by its magic bytes and decompressed.
+func ReadFASTA(r io.Reader) (records []FASTARecord, err error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("bioinf: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	var seq strings.Builder
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "":
+			continue
+		case strings.HasPrefix(text, ">"):
+			if len(records) > 0 {
+				records[len(records)-1].Seq = seq.String()
+			}
+			seq.Reset()
+			fields := strings.Fields(text[1:])
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("bioinf: line %d: empty FASTA header", line)
+			}
+			records = append(records, FASTARecord{ID: fields[0]})
+		case len(records) == 0:
+			return nil, fmt.Errorf("bioinf: line %d: sequence data before first FASTA header", line)
+		default:
+			seq.WriteString(text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		records[len(records)-1].Seq = seq.String()
+	}
+	return records, nil
+}
+
+// ReadFASTAFile is ReadFASTA on the file at path, which may be compressed
+func ReadFASTAFile(path string) ([]FASTARecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFASTA(f)
+}
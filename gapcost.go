@@ -0,0 +1,25 @@
+package bioinf
+
+import "math"
+
+// GapCostFunc returns the score, in doubled units and normally negative, of
+// a run of runLen consecutive gaps. Scoring by run rather than by position
+// needs to know where runs start and end, so it is only available for
+// evaluating finished alignments; the solvers score column by column.
+type GapCostFunc func(runLen int) int
+
+// AffineGapCost charges open for the first gap of a run and extend for every
+// further one.
+func AffineGapCost(open, extend int) GapCostFunc {
+	return func(runLen int) int {
+		return open + (runLen-1)*extend
+	}
+}
+
+// LogGapCost charges open + scale*ln(runLen), rounded, so the cost of a run
+// grows ever more slowly with its length. A run of one gap costs open.
+func LogGapCost(open, scale int) GapCostFunc {
+	return func(runLen int) int {
+		return open + int(math.Round(float64(scale)*math.Log(float64(runLen))))
+	}
+}
@@ -0,0 +1,80 @@
+package bioinf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadMatrix parses a substitution matrix such as BLOSUM62 in the NCBI text
+// format: '#' comment lines, a header line of one-letter codes and then one
+// row per code, starting with the code, of whitespace-separated integer
+// scores. Codes are read as amino acids, see Residue; the unknown residue X
+// scores N, and other codes outside AminoAcids, such as B, Z and '*', are
+// ignored. The returned func gives the score of a pair of residues, doubled
+// like every other score, and 0 for a pair the matrix lacks. It is an error
+// for the matrix to be malformed or asymmetric.
+func ReadMatrix(r io.Reader) (func(a, b Base) int, error) {
+	scanner := bufio.NewScanner(r)
+	var header []string
+	scores := map[[2]Base]int{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "#"):
+			continue
+		case header == nil:
+			header = fields
+			continue
+		case len(fields) != len(header)+1:
+			return nil, fmt.Errorf("bioinf: line %d: %d scores for %d columns", line, len(fields)-1, len(header))
+		}
+		row, ok := matrixCode(fields[0])
+		if !ok {
+			continue
+		}
+		for i, f := range fields[1:] {
+			col, ok := matrixCode(header[i])
+			if !ok {
+				continue
+			}
+			s, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("bioinf: line %d: %w", line, err)
+			}
+			scores[[2]Base{row, col}] = 2 * s
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("bioinf: no matrix header")
+	}
+	for pair, s := range scores {
+		if t, ok := scores[[2]Base{pair[1], pair[0]}]; !ok || t != s {
+			return nil, fmt.Errorf("bioinf: matrix is not symmetric at %v", pair)
+		}
+	}
+	return func(a, b Base) int {
+		return scores[[2]Base{a, b}]
+	}, nil
+}
+
+// matrixCode returns the residue of a matrix header or row code, ok false
+// for a code that is ignored
+func matrixCode(code string) (b Base, ok bool) {
+	switch {
+	case len(code) != 1:
+		return 0, false
+	case code == "X" || code == "x":
+		return N, true
+	case strings.Contains(AminoAcids, strings.ToUpper(code)):
+		return Residue(rune(code[0])), true
+	}
+	return 0, false
+}
@@ -0,0 +1,46 @@
+package msa
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// WriteA2M writes gapped rows in the A2M dialect of aligned FASTA used by
+// HMMER and the UCSC tools. matchColumns says, per alignment column, whether
+// it is a match column: there residues are written in uppercase and gaps
+// (deletions) as '-', while in insert columns residues are lowercase and
+// gaps '.'. Gaps in the input may be any of bio.GapChars. Each record is
+// written on two lines, header and row, without wrapping.
+func WriteA2M(w io.Writer, ids []string, aligned []string, matchColumns []bool) error {
+	if len(ids) != len(aligned) {
+		return fmt.Errorf("msa: %v ids for %v rows", len(ids), len(aligned))
+	}
+	for i, row := range aligned {
+		if len(row) != len(matchColumns) {
+			return fmt.Errorf("msa: row %v has length %v, expected %v match columns", i, len(row), len(matchColumns))
+		}
+	}
+	bw := bufio.NewWriter(w)
+	for i, row := range aligned {
+		var line strings.Builder
+		for col, r := range []byte(row) {
+			switch {
+			case bio.IsGap(rune(r)) && matchColumns[col]:
+				line.WriteByte('-')
+			case bio.IsGap(rune(r)):
+				line.WriteByte('.')
+			case matchColumns[col]:
+				line.WriteRune(unicode.ToUpper(rune(r)))
+			default:
+				line.WriteRune(unicode.ToLower(rune(r)))
+			}
+		}
+		fmt.Fprintf(bw, ">%v\n%v\n", ids[i], line.String())
+	}
+	return bw.Flush()
+}
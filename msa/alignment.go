@@ -0,0 +1,193 @@
+package msa
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// Alignment is a block of equal-length gapped sequences.
+type Alignment struct {
+	IDs   []string        // optional sequence identifiers, parallel to Rows
+	Rows  []*bio.Sequence // gapped sequences, gaps are bio.X
+	Score float64         // sum-of-pairs score of the alignment
+	// Exact is set by solvers when Score is the proven optimum of their
+	// objective for these sequences, as opposed to a heuristic result. It is
+	// false for parsed alignments and is cleared by edits and rescoring.
+	Exact bool
+
+	// per-column scores kept by Rescore for incremental edits, nil until used
+	cfg       bio.ScoreConfig
+	colScores []int64
+	total     int64 // sum of the column scores that are not bio.Forbidden
+	forbidden int   // number of bio.Forbidden columns
+}
+
+// NewAlignment builds an Alignment from gapped strings ('-' for a gap) and
+// scores it with the default sum-of-pairs scheme. ids may be nil.
+func NewAlignment(ids []string, rows []string) (*Alignment, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("msa: empty alignment")
+	}
+	if ids != nil && len(ids) != len(rows) {
+		return nil, fmt.Errorf("msa: %v ids for %v rows", len(ids), len(rows))
+	}
+	// compare parsed lengths: whitespace is skipped when parsing, so the
+	// byte lengths of the strings can agree while the rows do not
+	seqs := bio.AsToSeqs(rows)
+	for i, seq := range seqs {
+		if len(seq.Bases) != len(seqs[0].Bases) {
+			return nil, fmt.Errorf("msa: row %v has length %v, expected %v", i, len(seq.Bases), len(seqs[0].Bases))
+		}
+	}
+	a := &Alignment{IDs: ids, Rows: seqs}
+	a.Score = a.score(bio.DefaultScoreConfig())
+	return a, nil
+}
+
+// Width returns the number of columns in the alignment.
+func (a *Alignment) Width() int {
+	if len(a.Rows) == 0 {
+		return 0
+	}
+	return len(a.Rows[0].Bases)
+}
+
+// Column returns the bases of column i, one per row.
+func (a *Alignment) Column(i int) []bio.Base {
+	col := make([]bio.Base, len(a.Rows))
+	for j, row := range a.Rows {
+		col[j] = row.Bases[i]
+	}
+	return col
+}
+
+// Columns calls yield with each column in order until yield returns false,
+// so it can be ranged over directly: for col := range a.Columns { ... }.
+// Every column is a new slice; see SharedColumns to avoid the allocations.
+func (a *Alignment) Columns(yield func(col []bio.Base) bool) {
+	for i := 0; i < a.Width(); i++ {
+		if !yield(a.Column(i)) {
+			return
+		}
+	}
+}
+
+// SharedColumns is like Columns but fills the same slice for every column.
+// yield must not keep col past its own return.
+func (a *Alignment) SharedColumns(yield func(col []bio.Base) bool) {
+	col := make([]bio.Base, len(a.Rows))
+	for i := 0; i < a.Width(); i++ {
+		for j, row := range a.Rows {
+			col[j] = row.Bases[i]
+		}
+		if !yield(col) {
+			return
+		}
+	}
+}
+
+// ConservationTrack returns a 0-9 conservation value per column. For each
+// column f is the number of rows holding the most frequent non-gap base
+// divided by the total number of rows (gaps count against conservation), and
+// the value is floor(9*f): 9 means every row has the same base, 0 means the
+// column is all gaps or no base occurs in more than a ninth of the rows.
+// The CLUSTAL writer prints it under each block, see TrackLine.
+func (a *Alignment) ConservationTrack() []int {
+	track := make([]int, a.Width())
+	for i := range track {
+		track[i] = conservation(a.Column(i))
+	}
+	return track
+}
+
+// TrackLine formats a ConservationTrack as one digit per column, for
+// printing under the rows of an alignment
+func TrackLine(track []int) string {
+	line := make([]byte, len(track))
+	for i, v := range track {
+		line[i] = '0' + byte(v)
+	}
+	return string(line)
+}
+
+// conservation returns the ConservationTrack value of a single column
+func conservation(col []bio.Base) int {
+	if len(col) == 0 {
+		return 0
+	}
+	counts := map[bio.Base]int{}
+	most := 0
+	for _, b := range col {
+		if b == bio.X {
+			continue
+		}
+		counts[b]++
+		most = bio.Max(most, counts[b])
+	}
+	return 9 * most / len(col)
+}
+
+// TrimTerminal returns a copy of the alignment with leading and trailing
+// columns removed while their gap fraction exceeds maxGapFrac. Trimming stops
+// at the first column on each side that is within the threshold, so
+// interior columns are never removed. The trimmed columns are rescored
+// under cfg, which should be the scheme the alignment was made under.
+func (a *Alignment) TrimTerminal(maxGapFrac float64, cfg bio.ScoreConfig) *Alignment {
+	start, end := 0, a.Width()
+	for start < end && a.columnGapFraction(start) > maxGapFrac {
+		start++
+	}
+	for end > start && a.columnGapFraction(end-1) > maxGapFrac {
+		end--
+	}
+	return a.slice(start, end, cfg)
+}
+
+// columnGapFraction returns the fraction of rows with a gap in column i.
+func (a *Alignment) columnGapFraction(i int) float64 {
+	gaps := 0
+	for _, b := range a.Column(i) {
+		if b == bio.X {
+			gaps++
+		}
+	}
+	return float64(gaps) / float64(len(a.Rows))
+}
+
+// slice returns a copy holding columns [start, end), rescored under cfg.
+func (a *Alignment) slice(start, end int, cfg bio.ScoreConfig) *Alignment {
+	b := &Alignment{IDs: append([]string(nil), a.IDs...)}
+	for _, row := range a.Rows {
+		s := bio.NewSequence()
+		s.Bases = append(s.Bases, row.Bases[start:end]...)
+		b.Rows = append(b.Rows, s)
+	}
+	b.Score = b.score(cfg)
+	return b
+}
+
+// AppendColumns returns a new alignment with the columns of b placed after
+// those of a. Both must hold the same sequences in the same order: the row
+// counts must agree, and either neither carries IDs or both carry the same
+// IDs. Since column scores add up, the new Score is a.Score + b.Score.
+func (a *Alignment) AppendColumns(b *Alignment) (*Alignment, error) {
+	if len(a.Rows) != len(b.Rows) {
+		return nil, fmt.Errorf("msa: cannot append %v rows to %v rows", len(b.Rows), len(a.Rows))
+	}
+	if (a.IDs == nil) != (b.IDs == nil) || len(a.IDs) != len(b.IDs) {
+		return nil, fmt.Errorf("msa: cannot append %v ids to %v ids", len(b.IDs), len(a.IDs))
+	}
+	for i := range a.IDs {
+		if a.IDs[i] != b.IDs[i] {
+			return nil, fmt.Errorf("msa: row %v: id %q does not match %q", i, b.IDs[i], a.IDs[i])
+		}
+	}
+	c := &Alignment{IDs: append([]string(nil), a.IDs...), Score: a.Score + b.Score}
+	for i := range a.Rows {
+		s := bio.NewSequence()
+		s.Bases = append(append(s.Bases, a.Rows[i].Bases...), b.Rows[i].Bases...)
+		c.Rows = append(c.Rows, s)
+	}
+	return c, nil
+}
@@ -0,0 +1,38 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// Canonicalize left-aligns the gaps of the alignment in place, so that
+// co-optimal alignments differing only in where an equivalent gap sits come
+// out the same. The rule: rows are visited in order and each row's columns
+// left to right; wherever a gap has a base directly to its left in the same
+// row the two swap, provided the two columns then score the same under the
+// scheme of the last Rescore (or the default scheme) and neither is left
+// holding only gaps. Passes repeat until no gap moves. Score is unchanged,
+// and so is Exact.
+func (a *Alignment) Canonicalize() {
+	cfg := a.scoringConfig()
+	exact := a.Exact
+	pairScore := func(c int) int64 {
+		return bio.AddScores(bio.ColumnScore(a.Column(c-1), cfg), bio.ColumnScore(a.Column(c), cfg))
+	}
+	for moved := true; moved; {
+		moved = false
+		for _, row := range a.Rows {
+			for c := 1; c < a.Width(); c++ {
+				if row.Bases[c] != bio.X || row.Bases[c-1] == bio.X {
+					continue
+				}
+				before := pairScore(c)
+				row.Bases[c-1], row.Bases[c] = bio.X, row.Bases[c-1]
+				if pairScore(c) != before || allGaps(a.Column(c-1)) {
+					row.Bases[c-1], row.Bases[c] = row.Bases[c], bio.X
+					continue
+				}
+				moved = true
+			}
+		}
+	}
+	a.Rescore(cfg)
+	a.Exact = exact
+}
@@ -0,0 +1,103 @@
+package msa
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// MutualInformation returns the L×L matrix of mutual information, in bits,
+// between every pair of columns, ignoring rows with a gap in either column.
+// See MutualInformationWith.
+func (a *Alignment) MutualInformation() [][]float64 {
+	return a.MutualInformationWith(false, false)
+}
+
+// MutualInformationWith returns the mutual information in bits between
+// every pair of columns i != j,
+//
+//	MI(i, j) = sum over x, y of p(x, y) log2(p(x, y) / (p(x) p(y)))
+//
+// from the co-occurrence frequencies of bases x and y in the two columns.
+// With gapsAsSymbol a gap counts as a symbol of its own, otherwise rows with a
+// gap in either column are left out. With apc the average product
+// correction of Dunn et al. (2008) is subtracted,
+//
+//	MIp(i, j) = MI(i, j) - mean(MI(i, .)) mean(MI(., j)) / mean(MI)
+//
+// which removes most of the background MI caused by conservation and
+// phylogeny. The diagonal is 0.
+func (a *Alignment) MutualInformationWith(gapsAsSymbol, apc bool) [][]float64 {
+	width := a.Width()
+	mi := make([][]float64, width)
+	for i := range mi {
+		mi[i] = make([]float64, width)
+	}
+	for i := 0; i < width; i++ {
+		for j := i + 1; j < width; j++ {
+			mi[i][j] = a.columnMI(i, j, gapsAsSymbol)
+			mi[j][i] = mi[i][j]
+		}
+	}
+	if apc && width > 2 {
+		mi = applyAPC(mi)
+	}
+	return mi
+}
+
+// columnMI returns the mutual information of columns i and j
+func (a *Alignment) columnMI(i, j int, gapsAsSymbol bool) float64 {
+	const symbols = int(bio.N) + 1
+	var joint [symbols][symbols]float64
+	var pi, pj [symbols]float64
+	n := 0.0
+	for _, row := range a.Rows {
+		x, y := row.Bases[i], row.Bases[j]
+		if !gapsAsSymbol && (x == bio.X || y == bio.X) {
+			continue
+		}
+		joint[x][y]++
+		pi[x]++
+		pj[y]++
+		n++
+	}
+	sum := 0.0
+	for x := range joint {
+		for y, c := range joint[x] {
+			if c > 0 {
+				sum += c / n * math.Log2(c*n/(pi[x]*pj[y]))
+			}
+		}
+	}
+	return sum
+}
+
+// applyAPC returns mi with the average product correction applied to the
+// off-diagonal entries
+func applyAPC(mi [][]float64) [][]float64 {
+	width := len(mi)
+	rowMeans := make([]float64, width)
+	total := 0.0
+	for i := range mi {
+		for j, v := range mi[i] {
+			if i != j {
+				rowMeans[i] += v
+			}
+		}
+		total += rowMeans[i]
+		rowMeans[i] /= float64(width - 1)
+	}
+	mean := total / float64(width*(width-1))
+	corrected := make([][]float64, width)
+	for i := range mi {
+		corrected[i] = make([]float64, width)
+		for j, v := range mi[i] {
+			if i != j && mean > 0 {
+				corrected[i][j] = v - rowMeans[i]*rowMeans[j]/mean
+			} else if i != j {
+				corrected[i][j] = v
+			}
+		}
+	}
+	return corrected
+}
@@ -0,0 +1,86 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// Consensus returns the gapped consensus row of the alignment: each column's
+// most frequent of A, C, G and T, with ties going to the earlier base. An
+// ambiguous base counts fractionally for each base it stands for, so an R
+// adds half to A and half to G; IUPAC N, which stands for all four, carries
+// no information and counts like N. A column without any base is N if it
+// holds an N and a gap otherwise.
+func (a *Alignment) Consensus() *bio.Sequence {
+	bases := make([]bio.Base, a.Width())
+	for i := range bases {
+		bases[i] = consensusBase(a.Column(i))
+	}
+	return &bio.Sequence{Bases: bases}
+}
+
+// consensusBase returns the consensus of a single column, see Consensus.
+// counts are kept in twelfths so that a half or a third is exact.
+func consensusBase(col []bio.Base) bio.Base {
+	counts := make([]int, bio.AlphabetSize)
+	unknown := false
+	for _, b := range col {
+		switch nucleotides := b.Nucleotides(); {
+		case len(nucleotides) > 0 && len(nucleotides) < bio.AlphabetSize:
+			for _, n := range nucleotides {
+				counts[n] += 12 / len(nucleotides)
+			}
+		case b == bio.N || len(nucleotides) == bio.AlphabetSize:
+			unknown = true
+		}
+	}
+	best := bio.X
+	for _, b := range bio.ConcreteBases() {
+		if counts[b] > 0 && (best == bio.X || counts[b] > counts[best]) {
+			best = b
+		}
+	}
+	if best == bio.X && unknown {
+		return bio.N
+	}
+	return best
+}
+
+// IdentityToConsensus returns the percent identity (0-100) of each row to
+// the Consensus, compared over the columns where neither holds a gap. Low
+// values pick out the rows least representative of the group.
+func (a *Alignment) IdentityToConsensus() []float64 {
+	consensus := a.Consensus().Bases
+	identities := make([]float64, len(a.Rows))
+	for i, row := range a.Rows {
+		identities[i] = percentIdentity(row.Bases, consensus, ExcludeGaps)
+	}
+	return identities
+}
+
+// DegenerateConsensus returns, per column, the IUPAC code covering exactly
+// the column's bases, e.g. R for a column of A and G. Gaps are ignored, and
+// a column without bases is written '-'. See DegenerateConsensusWith.
+func (a *Alignment) DegenerateConsensus() string {
+	return a.DegenerateConsensusWith(1)
+}
+
+// DegenerateConsensusWith is like DegenerateConsensus but writes '-' for
+// every column whose gap fraction exceeds maxGapFrac, so with 0 any gap
+// makes the consensus a gap. An ambiguous base covers the bases it stands
+// for, and an N in a column covers every base and makes its code N.
+func (a *Alignment) DegenerateConsensusWith(maxGapFrac float64) string {
+	codes := make([]byte, a.Width())
+	for i := range codes {
+		if a.columnGapFraction(i) > maxGapFrac {
+			codes[i] = '-'
+			continue
+		}
+		col := a.Column(i)
+		for _, b := range col {
+			if b == bio.N {
+				col = bio.ConcreteBases()
+				break
+			}
+		}
+		codes[i] = bio.AmbiguityCode(col...).String()[0]
+	}
+	return string(codes)
+}
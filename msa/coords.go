@@ -0,0 +1,66 @@
+package msa
+
+import (
+	"strconv"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// CoordinateMap returns, for each residue of row seqIndex in its original
+// ungapped sequence, the alignment column holding it. Gaps have no entry,
+// so the result has one element per residue.
+func (a *Alignment) CoordinateMap(seqIndex int) []int {
+	var cols []int
+	for col, b := range a.Rows[seqIndex].Bases {
+		if b != bio.X {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// AnnotatedRows is the inverse of CoordinateMap: for every row it gives, per
+// alignment column, the 0-based position of the residue in the original
+// ungapped sequence, or -1 where the row has a gap.
+func (a *Alignment) AnnotatedRows() [][]int {
+	rows := make([][]int, len(a.Rows))
+	for i, row := range a.Rows {
+		pos := 0
+		rows[i] = make([]int, len(row.Bases))
+		for col, b := range row.Bases {
+			if b == bio.X {
+				rows[i][col] = -1
+				continue
+			}
+			rows[i][col] = pos
+			pos++
+		}
+	}
+	return rows
+}
+
+// Blocks returns, for every row, the alignment columns spanned by each of
+// its ungapped runs as half-open [start, end) ranges in the manner of BED.
+// Rows are keyed by ID, or by their index ("0", "1", ...) when the alignment
+// has no IDs; rows sharing an ID share an entry, the later row winning.
+func (a *Alignment) Blocks() map[string][][2]int {
+	blocks := make(map[string][][2]int, len(a.Rows))
+	for i, row := range a.Rows {
+		key := strconv.Itoa(i)
+		if a.IDs != nil {
+			key = a.IDs[i]
+		}
+		var runs [][2]int
+		for col, b := range row.Bases {
+			switch {
+			case b == bio.X:
+			case col > 0 && row.Bases[col-1] != bio.X:
+				runs[len(runs)-1][1] = col + 1
+			default:
+				runs = append(runs, [2]int{col, col + 1})
+			}
+		}
+		blocks[key] = runs
+	}
+	return blocks
+}
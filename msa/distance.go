@@ -0,0 +1,37 @@
+package msa
+
+import "math"
+
+// DistanceModel selects how a pairwise similarity is turned into an
+// evolutionary distance for tree building.
+type DistanceModel int
+
+// Identity ... enum represents a distance model
+const (
+	// Identity uses the observed fraction of differences, 1 - score/maxScore.
+	Identity DistanceModel = iota
+	// JukesCantor corrects the observed fraction of differences p for
+	// multiple substitutions at a site: -3/4 ln(1 - 4/3 p).
+	JukesCantor
+)
+
+// ConvertDistance converts a pairwise score into a distance under model,
+// treating score/maxScore as the fraction of identical sites. It returns NaN
+// if maxScore is not positive or score is outside [0, maxScore]. The
+// Jukes-Cantor correction is only defined while fewer than 3/4 of the sites
+// differ; beyond that the sequences are saturated and the distance is +Inf.
+func ConvertDistance(score, maxScore float64, model DistanceModel) float64 {
+	if maxScore <= 0 || score < 0 || score > maxScore {
+		return math.NaN()
+	}
+	p := 1 - score/maxScore
+	switch model {
+	case JukesCantor:
+		if p >= 0.75 {
+			return math.Inf(1)
+		}
+		return -0.75 * math.Log(1-p*4/3)
+	default:
+		return p
+	}
+}
@@ -0,0 +1,165 @@
+package msa
+
+import (
+	"fmt"
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// Rescore scores every column under cfg, sets Score to the total and keeps
+// the column scores so that RescoreColumn and MoveGap can update Score
+// without rescoring the whole alignment. Call it again after changing Rows
+// directly; the cache only follows changes made through those methods.
+func (a *Alignment) Rescore(cfg bio.ScoreConfig) {
+	a.cfg = cfg
+	a.Exact = false
+	a.colScores = make([]int64, a.Width())
+	a.total, a.forbidden = 0, 0
+	for i := range a.colScores {
+		a.colScores[i] = a.columnScore(i, cfg)
+		a.tally(a.colScores[i], 1)
+	}
+	a.updateScore()
+}
+
+// RescoreColumn recomputes the score of column col, in doubled units, updates
+// Score by the difference and returns the new column score. The alignment
+// is scored with the default scheme first if Rescore has not been called.
+func (a *Alignment) RescoreColumn(col int) int64 {
+	if a.colScores == nil {
+		a.Rescore(bio.DefaultScoreConfig())
+	}
+	a.Exact = false
+	a.tally(a.colScores[col], -1)
+	a.colScores[col] = a.columnScore(col, a.cfg)
+	a.tally(a.colScores[col], 1)
+	a.updateScore()
+	return a.colScores[col]
+}
+
+// WorstColumn returns the lowest-scoring column and its score in doubled
+// units, the earliest on ties, or -1, 0 for an alignment without columns.
+// Columns are scored as by columnScores; the alignment is not modified.
+func (a *Alignment) WorstColumn() (index int, score int64) {
+	index = -1
+	for i, s := range a.columnScores() {
+		if index < 0 || s < score {
+			index, score = i, s
+		}
+	}
+	return
+}
+
+// CenteredColumnScores returns the column scores, in doubled units, less
+// baseline, so that columns scoring above it are positive and those below
+// negative. BaselineColumnScore is a sensible baseline. Forbidden columns
+// stay Forbidden.
+func (a *Alignment) CenteredColumnScores(baseline int64) []int64 {
+	scores := a.columnScores()
+	for i, s := range scores {
+		if s != bio.Forbidden {
+			scores[i] = s - baseline
+		}
+	}
+	return scores
+}
+
+// BaselineColumnScore returns the expected score, in doubled units and
+// rounded, of a gapless column of as many residues as the alignment has
+// rows, each drawn independently from the alignment's overall composition
+// of A, C, G and T: the score of a column of random rather than
+// homologous residues. Since it grows with the number of pairs it makes
+// centered scores comparable across alignments of different depths. Forbidden
+// pairs are left out of the expectation.
+func (a *Alignment) BaselineColumnScore() int64 {
+	counts := make([]float64, bio.AlphabetSize)
+	total := 0.0
+	for _, row := range a.Rows {
+		for _, b := range row.Bases {
+			if b >= bio.A && b <= bio.T {
+				counts[b]++
+				total++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	cfg := a.scoringConfig()
+	pair := 0.0
+	for _, b := range bio.ConcreteBases() {
+		for _, c := range bio.ConcreteBases() {
+			if s := cfg.PairScore(b, c); s != bio.Forbidden {
+				pair += counts[b] / total * counts[c] / total * float64(s)
+			}
+		}
+	}
+	pairs := len(a.Rows) * (len(a.Rows) - 1) / 2
+	return int64(math.Round(float64(pairs) * pair))
+}
+
+// columnScores returns the score of every column in doubled units under the
+// scheme of the last Rescore, or the default scheme if there was none
+func (a *Alignment) columnScores() []int64 {
+	if a.colScores != nil {
+		return append([]int64(nil), a.colScores...)
+	}
+	scores := make([]int64, a.Width())
+	for i := range scores {
+		scores[i] = bio.ColumnScore(a.Column(i), bio.DefaultScoreConfig())
+	}
+	return scores
+}
+
+// scoringConfig returns the scheme columnScores scores under
+func (a *Alignment) scoringConfig() bio.ScoreConfig {
+	if a.colScores != nil {
+		return a.cfg
+	}
+	return bio.DefaultScoreConfig()
+}
+
+// tally adds (sign 1) or removes (sign -1) a column score from the running
+// total. Forbidden columns are counted apart so they can be removed again.
+func (a *Alignment) tally(score int64, sign int) {
+	if score == bio.Forbidden {
+		a.forbidden += sign
+	} else {
+		a.total += int64(sign) * score
+	}
+}
+
+func (a *Alignment) updateScore() {
+	if a.forbidden > 0 {
+		a.Score = bio.ToNatural(bio.Forbidden)
+	} else {
+		a.Score = bio.ToNatural(a.total)
+	}
+}
+
+// MoveGap drags the gap at column from of row seq to column to, shifting the
+// row's bases in between by one column towards from. The width and the
+// order of the bases are unchanged, and only the columns in between are
+// rescored. It is an error if row seq has no gap at from.
+func (a *Alignment) MoveGap(seq, from, to int) error {
+	row := a.Rows[seq].Bases
+	if from < 0 || from >= len(row) || to < 0 || to >= len(row) {
+		return fmt.Errorf("msa: gap move %v -> %v outside %v columns", from, to, len(row))
+	}
+	if row[from] != bio.X {
+		return fmt.Errorf("msa: row %v has no gap at column %v", seq, from)
+	}
+	lo, hi := from, to
+	if from < to {
+		copy(row[from:to], row[from+1:to+1])
+	} else {
+		copy(row[to+1:from+1], row[to:from])
+		lo, hi = to, from
+	}
+	row[to] = bio.X
+	for col := lo; col <= hi; col++ {
+		a.RescoreColumn(col)
+	}
+	return nil
+}
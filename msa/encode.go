@@ -0,0 +1,208 @@
+package msa
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// encodingMagic starts every encoded alignment, the last byte is the version
+const encodingMagic = "ALN\x01"
+
+// bits of the flags byte
+const (
+	flagIDs = 1 << iota
+	flagExact
+)
+
+// run kinds in the encoding of a row
+const (
+	runBases = iota // ACGT, packed four to a byte
+	runGaps
+	runUnknown // bio.N
+)
+
+// Encode writes a compact binary encoding of the alignment to w:
+//
+//	magic "ALN\x01"
+//	uvarint rows, uvarint width, flags byte: 1 if IDs follow, 2 if Exact
+//	per ID: uvarint length, bytes
+//	Score as the little-endian bits of a float64
+//	per row: runs until width columns are covered, each a uvarint
+//	  length<<2 | kind followed, for a run of bases, by the bases packed
+//	  two bits each (A=0 ... T=3), low bits first
+//
+// gap and N runs carry no data, so gappy rows cost a few bytes per run.
+func (a *Alignment) Encode(w io.Writer) error {
+	buf := []byte(encodingMagic)
+	buf = binary.AppendUvarint(buf, uint64(len(a.Rows)))
+	buf = binary.AppendUvarint(buf, uint64(a.Width()))
+	var flags byte
+	if a.IDs != nil {
+		flags |= flagIDs
+	}
+	if a.Exact {
+		flags |= flagExact
+	}
+	buf = append(buf, flags)
+	for _, id := range a.IDs {
+		buf = binary.AppendUvarint(buf, uint64(len(id)))
+		buf = append(buf, id...)
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(a.Score))
+	for i, row := range a.Rows {
+		for start := 0; start < len(row.Bases); {
+			kind, err := runKind(row.Bases[start])
+			if err != nil {
+				return fmt.Errorf("msa: row %v column %v: %w", i, start, err)
+			}
+			end := start + 1
+			for end < len(row.Bases) {
+				if k, _ := runKind(row.Bases[end]); k != kind {
+					break
+				}
+				end++
+			}
+			buf = binary.AppendUvarint(buf, uint64(end-start)<<2|uint64(kind))
+			if kind == runBases {
+				packed := make([]byte, (end-start+3)/4)
+				for j, b := range row.Bases[start:end] {
+					packed[j/4] |= byte(b) << (2 * (j % 4))
+				}
+				buf = append(buf, packed...)
+			}
+			start = end
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func runKind(b bio.Base) (int, error) {
+	switch {
+	case int(b) < bio.AlphabetSize && b >= bio.A:
+		return runBases, nil
+	case b == bio.X:
+		return runGaps, nil
+	case b == bio.N:
+		return runUnknown, nil
+	default:
+		return 0, fmt.Errorf("invalid base %d", b)
+	}
+}
+
+// DecodeAlignment reads an alignment written by Encode. Reads are buffered
+// unless r is an io.ByteReader, so r may be read past the end of the
+// alignment. The counts in the input are not trusted: IDs and packed bases
+// take memory only as their bytes arrive, so a truncated input is an error
+// rather than a large allocation. Runs of gaps and N carry no data, though,
+// so a short input may still decode to a wide alignment.
+func DecodeAlignment(r io.Reader) (*Alignment, error) {
+	br, ok := r.(interface {
+		io.Reader
+		io.ByteReader
+	})
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	magic := make([]byte, len(encodingMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != encodingMagic {
+		return nil, errors.New("msa: not an encoded alignment")
+	}
+	rows, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	width, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if rows > math.MaxInt32 || width > math.MaxInt32 {
+		return nil, fmt.Errorf("msa: implausible size %v × %v", rows, width)
+	}
+	a := &Alignment{}
+	flags, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	a.Exact = flags&flagExact != 0
+	if flags&flagIDs != 0 {
+		for i := uint64(0); i < rows; i++ {
+			n, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, err
+			}
+			if n > math.MaxInt32 {
+				return nil, fmt.Errorf("msa: implausible id length %v", n)
+			}
+			id, err := readBytes(br, n)
+			if err != nil {
+				return nil, err
+			}
+			a.IDs = append(a.IDs, string(id))
+		}
+	}
+	var bits [8]byte
+	if _, err := io.ReadFull(br, bits[:]); err != nil {
+		return nil, err
+	}
+	a.Score = math.Float64frombits(binary.LittleEndian.Uint64(bits[:]))
+	for i := uint64(0); i < rows; i++ {
+		row := bio.NewSequence()
+		for uint64(len(row.Bases)) < width {
+			header, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, err
+			}
+			n, kind := header>>2, header&3
+			if n == 0 || n > width-uint64(len(row.Bases)) {
+				return nil, fmt.Errorf("msa: row %v: bad run length %v", i, n)
+			}
+			switch kind {
+			case runBases:
+				packed, err := readBytes(br, (n+3)/4)
+				if err != nil {
+					return nil, err
+				}
+				for j := uint64(0); j < n; j++ {
+					row.Bases = append(row.Bases, bio.Base(packed[j/4]>>(2*(j%4))&3))
+				}
+			case runGaps, runUnknown:
+				b := bio.X
+				if kind == runUnknown {
+					b = bio.N
+				}
+				for j := uint64(0); j < n; j++ {
+					row.Bases = append(row.Bases, b)
+				}
+			default:
+				return nil, fmt.Errorf("msa: row %v: bad run kind %v", i, kind)
+			}
+		}
+		a.Rows = append(a.Rows, row)
+	}
+	return a, nil
+}
+
+// readBytes reads exactly n bytes from r. n comes from the input, so the
+// buffer grows with the bytes actually read rather than being allocated up
+// front: a hostile or truncated header fails with io.ErrUnexpectedEOF once
+// the input runs out instead of claiming n bytes of memory first.
+func readBytes(r io.Reader, n uint64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(b)) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
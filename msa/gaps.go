@@ -0,0 +1,82 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// GapOpens returns the number of gap runs summed over all rows. Under an
+// affine model each run is charged the open penalty once.
+func (a *Alignment) GapOpens() int {
+	opens, _ := a.gapCounts()
+	return opens
+}
+
+// GapExtends returns the number of gap positions that continue a run, summed
+// over all rows, i.e. the positions charged the extend penalty. A run of
+// length k contributes one open and k-1 extends.
+func (a *Alignment) GapExtends() int {
+	_, extends := a.gapCounts()
+	return extends
+}
+
+func (a *Alignment) gapCounts() (opens, extends int) {
+	for _, row := range a.Rows {
+		for i, b := range row.Bases {
+			if b != bio.X {
+				continue
+			}
+			if i > 0 && row.Bases[i-1] == bio.X {
+				extends++
+			} else {
+				opens++
+			}
+		}
+	}
+	return
+}
+
+// TerminalGapMask returns, for every row and column, whether the position is
+// a terminal gap: a gap before the row's first base or after its last one.
+// Internal gaps are false. A row without bases is entirely terminal gaps.
+func (a *Alignment) TerminalGapMask() [][]bool {
+	mask := make([][]bool, len(a.Rows))
+	for i, row := range a.Rows {
+		mask[i] = make([]bool, len(row.Bases))
+		for j := 0; j < len(row.Bases) && row.Bases[j] == bio.X; j++ {
+			mask[i][j] = true
+		}
+		for j := len(row.Bases) - 1; j >= 0 && row.Bases[j] == bio.X; j-- {
+			mask[i][j] = true
+		}
+	}
+	return mask
+}
+
+// GapFraction returns the fraction of all cells (rows × columns) that are
+// gaps, 0 for an empty alignment.
+func (a *Alignment) GapFraction() float64 {
+	gaps := 0
+	for _, row := range a.Rows {
+		for _, b := range row.Bases {
+			if b == bio.X {
+				gaps++
+			}
+		}
+	}
+	if gaps == 0 {
+		return 0
+	}
+	return float64(gaps) / float64(len(a.Rows)*a.Width())
+}
+
+// Occupancy returns, per column, the number of rows holding a residue rather
+// than a gap. N counts as a residue.
+func (a *Alignment) Occupancy() []int {
+	occupancy := make([]int, a.Width())
+	for _, row := range a.Rows {
+		for i, b := range row.Bases {
+			if b != bio.X {
+				occupancy[i]++
+			}
+		}
+	}
+	return occupancy
+}
@@ -0,0 +1,114 @@
+package msa
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// GuidedProgressive aligns the sequences progressively along a UPGMA guide
+// tree of the distance matrix dm: the two closest clusters are joined first
+// and every join globally aligns the profiles of the two subtrees, scoring
+// each column with bio.ColumnScore. Rows come back in input order. It is a
+// heuristic for inputs too large for the exact solver, so the result is not
+// optimal in general. It is scored under cfg.
+func GuidedProgressive(seqStrings []string, dm [][]float64, cfg bio.ScoreConfig) (*Alignment, error) {
+	if len(seqStrings) == 0 {
+		return nil, fmt.Errorf("msa: no sequences to align")
+	}
+	if len(dm) != len(seqStrings) {
+		return nil, fmt.Errorf("msa: %v distances for %v sequences", len(dm), len(seqStrings))
+	}
+	seqs := cfg.Sequences(seqStrings)
+	rows, leaves := alignSubtree(upgma(dm), seqs, cfg)
+	a := &Alignment{Rows: make([]*bio.Sequence, len(seqs))}
+	for k, i := range leaves {
+		a.Rows[i] = rows[k]
+	}
+	a.Score = a.score(cfg)
+	return a, nil
+}
+
+// alignSubtree returns the alignment of the leaves below t, one row per leaf
+// in the order of leaves
+func alignSubtree(t *upgmaNode, seqs []*bio.Sequence, cfg bio.ScoreConfig) (rows []*bio.Sequence, leaves []int) {
+	if t.leaf >= 0 {
+		return []*bio.Sequence{{Bases: append([]bio.Base(nil), seqs[t.leaf].Bases...)}}, []int{t.leaf}
+	}
+	left, ll := alignSubtree(t.left, seqs, cfg)
+	right, rl := alignSubtree(t.right, seqs, cfg)
+	return alignProfiles(left, right, cfg), append(ll, rl...)
+}
+
+// alignProfiles globally aligns two alignments column against column and
+// returns the rows of a followed by those of b. Ties prefer pairing two
+// columns, then gapping b, then gapping a.
+func alignProfiles(a, b []*bio.Sequence, cfg bio.ScoreConfig) []*bio.Sequence {
+	colA := func(i int) []bio.Base { return columnOf(a, i) }
+	colB := func(j int) []bio.Base { return columnOf(b, j) }
+	gapsA, gapsB := gapColumn(len(a)), gapColumn(len(b))
+	join := func(x, y []bio.Base) []bio.Base {
+		return append(append([]bio.Base(nil), x...), y...)
+	}
+	wa, wb := len(a[0].Bases), len(b[0].Bases)
+	f := bio.Slice2D(wa+1, wb+1, int64(0))
+	for i := range f {
+		for j := range f[i] {
+			if i == 0 && j == 0 {
+				continue
+			}
+			best := bio.Forbidden
+			if i > 0 && j > 0 {
+				best = bio.MaxScore(best, bio.AddScores(f[i-1][j-1], bio.ColumnScore(join(colA(i-1), colB(j-1)), cfg)))
+			}
+			if i > 0 {
+				best = bio.MaxScore(best, bio.AddScores(f[i-1][j], bio.ColumnScore(join(colA(i-1), gapsB), cfg)))
+			}
+			if j > 0 {
+				best = bio.MaxScore(best, bio.AddScores(f[i][j-1], bio.ColumnScore(join(gapsA, colB(j-1)), cfg)))
+			}
+			f[i][j] = best
+		}
+	}
+
+	var cols [][]bio.Base // collected back to front
+	for i, j := wa, wb; i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && f[i][j] == bio.AddScores(f[i-1][j-1], bio.ColumnScore(join(colA(i-1), colB(j-1)), cfg)):
+			cols = append(cols, join(colA(i-1), colB(j-1)))
+			i, j = i-1, j-1
+		case i > 0 && f[i][j] == bio.AddScores(f[i-1][j], bio.ColumnScore(join(colA(i-1), gapsB), cfg)):
+			cols = append(cols, join(colA(i-1), gapsB))
+			i--
+		default:
+			cols = append(cols, join(gapsA, colB(j-1)))
+			j--
+		}
+	}
+	rows := make([]*bio.Sequence, len(a)+len(b))
+	for r := range rows {
+		rows[r] = bio.NewSequence()
+		for c := len(cols) - 1; c >= 0; c-- {
+			rows[r].Bases = append(rows[r].Bases, cols[c][r])
+		}
+	}
+	return rows
+}
+
+// columnOf returns column i of rows
+func columnOf(rows []*bio.Sequence, i int) []bio.Base {
+	col := make([]bio.Base, len(rows))
+	for r, row := range rows {
+		col[r] = row.Bases[i]
+	}
+	return col
+}
+
+// gapColumn returns a column of n gaps
+func gapColumn(n int) []bio.Base {
+	col := make([]bio.Base, n)
+	for i := range col {
+		col[i] = bio.X
+	}
+	return col
+}
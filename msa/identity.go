@@ -0,0 +1,70 @@
+package msa
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// IdentityConvention selects how PercentIdentity treats gaps. Tools differ
+// here, so identities are only comparable under the same convention.
+type IdentityConvention int
+
+const (
+	// ExcludeGaps ignores every column where either row has a gap, so
+	// identity is measured over aligned residue pairs only.
+	ExcludeGaps IdentityConvention = iota
+	// GapsMismatch counts a residue aligned to a gap as a mismatch. Columns
+	// where both rows are gapped are still ignored.
+	GapsMismatch
+)
+
+// PercentIdentity returns the percentage (0-100) of compared columns in which
+// rows i and j hold the same base. It returns 0 if no columns are compared.
+func (a *Alignment) PercentIdentity(i, j int, conv IdentityConvention) float64 {
+	return percentIdentity(a.Rows[i].Bases, a.Rows[j].Bases, conv)
+}
+
+// percentIdentity is PercentIdentity for two gapped rows of the same length
+func percentIdentity(ri, rj []bio.Base, conv IdentityConvention) float64 {
+	same, compared := 0, 0
+	for k, bi := range ri {
+		bj := rj[k]
+		if bi == bio.X && bj == bio.X {
+			continue
+		}
+		if (bi == bio.X || bj == bio.X) && conv == ExcludeGaps {
+			continue
+		}
+		compared++
+		if bi == bj {
+			same++
+		}
+	}
+	if compared == 0 {
+		return 0
+	}
+	return 100 * float64(same) / float64(compared)
+}
+
+// MostDivergent returns the row with the lowest mean PercentIdentity
+// (ExcludeGaps) to the other rows, and that mean. Ties go to the lower index.
+// With fewer than two rows there is nothing to compare and it returns -1, 0.
+func (a *Alignment) MostDivergent() (index int, meanIdentity float64) {
+	if len(a.Rows) < 2 {
+		return -1, 0
+	}
+	index, meanIdentity = -1, math.Inf(1)
+	for i := range a.Rows {
+		sum := 0.0
+		for j := range a.Rows {
+			if i != j {
+				sum += a.PercentIdentity(i, j, ExcludeGaps)
+			}
+		}
+		if mean := sum / float64(len(a.Rows)-1); mean < meanIdentity {
+			index, meanIdentity = i, mean
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// SolveAffine returns the score of the optimal global alignment with affine
+// gaps: a gap in a sequence costs open, against each base in its column,
+// where the sequence held a base (or had not started) in the previous
+// column, and extend where it continues a gap from there. pairs of bases
+// score as under cfg and cfg.Gap is not used; open and extend are doubled
+// like the other scores and normally negative, with open below extend. for
+// two sequences this is the usual affine score; for more it is the
+// quasi-natural approximation, which looks at each sequence's previous
+// column rather than at each projected pair.
+//
+// the cost of a column depends on the one before it, so each cell of the DP
+// is solved once for every possible last column, 2^n - 1 times as many
+// states, each looking at every previous column.
+func SolveAffine(seqStrings []string, cfg bio.ScoreConfig, open, extend int) float64 {
+	m := newAffineDP(cfg.Sequences(seqStrings), cfg, open, extend)
+	best := int64(0)
+	if !m.isBaseCase(m.maxIndices()) {
+		best = bio.Forbidden
+		for k := range m.subsetMasks {
+			best = bio.MaxScore(best, m.affineScore(m.maxIndices(), k))
+		}
+	}
+	return bio.ToNatural(best)
+}
+
+// affineDP extends the DP with the index of the last column's mask
+type affineDP struct {
+	*multiDP
+	open, extend int
+	scores       store // indexed by the cell and then the last mask
+	cached       store
+}
+
+func newAffineDP(seqs []*bio.Sequence, cfg bio.ScoreConfig, open, extend int) *affineDP {
+	m := &affineDP{multiDP: newMultiDP(seqs, cfg), open: open, extend: extend}
+	m.global = true
+	dims := append(m.dims(), len(m.subsetMasks))
+	m.scores, m.cached = newStore(dims), newStore(dims)
+	return m
+}
+
+// affineScore returns the best score of the alignments of the prefixes
+// ending at idxs whose last column is subsetMasks[k], Forbidden if there is
+// no such alignment
+func (m *affineDP) affineScore(idxs []int, k int) (best int64) {
+	mIdxs, ok := maskedIdxs(idxs, m.subsetMasks[k])
+	if !ok {
+		return bio.Forbidden
+	}
+	key := append(cpy(idxs), k)
+	if m.cached.At(key) == 1 {
+		return m.scores.At(key)
+	}
+	bases := m.maskedBases(idxs, m.subsetMasks[k])
+	if m.isBaseCase(mIdxs) {
+		best = m.affineColumn(bases, nil)
+	} else {
+		best = bio.Forbidden
+		for p, prev := range m.subsetMasks {
+			best = bio.MaxScore(best, bio.AddScores(m.affineScore(mIdxs, p), m.affineColumn(bases, prev)))
+		}
+	}
+	m.scores.Set(best, key)
+	m.cached.Set(1, key)
+	m.cells++
+	return
+}
+
+// affineColumn scores a column given the mask of the column before it, nil
+// for the first column
+func (m *affineDP) affineColumn(bases []bio.Base, prev []int) (sum int64) {
+	for i, bi := range bases {
+		for j := i + 1; j < len(bases); j++ {
+			bj := bases[j]
+			switch {
+			case bi == bio.X && bj == bio.X:
+				sum = bio.AddScores(sum, int64(m.cfg.GapGap))
+			case bi == bio.X:
+				sum = bio.AddScores(sum, m.gapCost(prev, i))
+			case bj == bio.X:
+				sum = bio.AddScores(sum, m.gapCost(prev, j))
+			default:
+				sum = bio.AddScores(sum, m.cfg.PairScore(bi, bj))
+			}
+		}
+	}
+	return
+}
+
+// gapCost is the cost of a gap in sequence i, extend if it was also gapped
+// in the previous column
+func (m *affineDP) gapCost(prev []int, i int) int64 {
+	if prev != nil && prev[i] == 0 {
+		return int64(m.extend)
+	}
+	return int64(m.open)
+}
@@ -0,0 +1,26 @@
+package mdp
+
+import (
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// Alignable is a cheap pre-check before an exact multiple alignment: it
+// globally aligns every pair of sequences under the default scores, which
+// costs the product of two lengths rather than of all of them, and reports
+// whether each pair has at least minIdentity percent identity (0-100, as
+// msa.Alignment.PercentIdentity with msa.GapsMismatch). it returns false as
+// soon as one pair falls below the threshold.
+func Alignable(seqStrings []string, minIdentity float64) bool {
+	seqs := bio.AsToSeqs(seqStrings)
+	for i := range seqs {
+		for j := i + 1; j < len(seqs); j++ {
+			mdp := newMultiDP([]*bio.Sequence{seqs[i], seqs[j]}, bio.DefaultScoreConfig())
+			mdp.global = true
+			if mdp.alignment(nil).PercentIdentity(0, 1, msa.GapsMismatch) < minIdentity {
+				return false
+			}
+		}
+	}
+	return true
+}
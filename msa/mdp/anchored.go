@@ -0,0 +1,64 @@
+package mdp
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// MaxBlockCells caps the DP table size of a single block in SolveAnchored.
+var MaxBlockCells int64 = 1 << 24
+
+// SolveAnchored aligns long sequences by cutting them at shared anchors and
+// aligning each block between consecutive anchors exactly and globally, as
+// SolveGlobal does. anchors[k][i] is a cut point in sequence i, the number of
+// its residues before anchor k; in every sequence the cut points must not
+// decrease from one anchor to the next. the blocks are joined with
+// AppendColumns, so Score is the global score of the joined alignment.
+// an error is returned for inconsistent anchors, more than MaxSequences
+// sequences or a block whose table exceeds MaxBlockCells.
+func SolveAnchored(seqStrings []string, anchors [][]int, cfg bio.ScoreConfig) (*msa.Alignment, error) {
+	if len(seqStrings) > MaxSequences {
+		return nil, fmt.Errorf("%w: %v sequences, MaxSequences is %v", ErrTooManySequences, len(seqStrings), MaxSequences)
+	}
+	seqs := cfg.Sequences(seqStrings)
+	prev := make([]int, len(seqs))
+	a := alignmentFromColumns(nil, len(seqs))
+	for k := 0; k <= len(anchors); k++ {
+		var anchor []int
+		if k < len(anchors) {
+			anchor = anchors[k]
+		} else {
+			// the last block runs to the end of every sequence
+			for _, seq := range seqs {
+				anchor = append(anchor, len(seq.Bases))
+			}
+		}
+		if len(anchor) != len(seqs) {
+			return nil, fmt.Errorf("mdp: anchor %v has %v positions for %v sequences", k, len(anchor), len(seqs))
+		}
+		block := make([]*bio.Sequence, len(seqs))
+		cells := int64(1)
+		for i, pos := range anchor {
+			if pos < prev[i] || pos > len(seqs[i].Bases) {
+				return nil, fmt.Errorf("mdp: anchor %v: position %v in sequence %v is out of order or range", k, pos, i)
+			}
+			block[i] = &bio.Sequence{Bases: seqs[i].Bases[prev[i]:pos]}
+			cells *= int64(pos - prev[i] + 1)
+		}
+		if cells > MaxBlockCells {
+			return nil, fmt.Errorf("mdp: block %v has %v cells, MaxBlockCells is %v", k, cells, MaxBlockCells)
+		}
+		mdp := newMultiDP(block, cfg)
+		mdp.global = true
+		b, err := a.AppendColumns(mdp.alignment(nil))
+		if err != nil {
+			return nil, err
+		}
+		a, prev = b, anchor
+	}
+	// the anchors constrain the alignment, without any it is one exact block
+	a.Exact = len(anchors) == 0
+	return a, nil
+}
@@ -0,0 +1,39 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// SolveBanded is like Solve but only visits cells inside a diagonal band:
+// for every pair of sequences i, j the offset idx_j - idx_i must stay within
+// bandWidth of the range between 0 and len_j - len_i, the diagonals of the
+// origin and of the final cell, which are therefore always inside. moves
+// leaving the band are skipped before recursing, so only in-band cells are
+// computed, and tables above DenseLimit store only those.
+//
+// the offset of a pair grows by one for every residue of j aligned to a gap
+// in i and shrinks for the reverse, so an alignment stays in the band iff no
+// pair's gap imbalance strays further than bandWidth outside the length
+// difference. the result equals Solve whenever some optimal alignment does,
+// and always for a bandWidth of at least the longest length; otherwise it
+// may be lower, never higher. a negative bandWidth is treated as 0.
+func SolveBanded(seqStrings []string, bandWidth int) float64 {
+	mdp := newMultiDP(bio.AsToSeqs(seqStrings), bio.DefaultScoreConfig())
+	mdp.allowed = mdp.inBand(max(bandWidth, 0))
+	return mdp.solve()
+}
+
+// inBand returns the allowed func keeping moves that end inside the band
+func (m *multiDP) inBand(width int) func(idxs, mask []int) bool {
+	lens := m.maxIndices()
+	return func(idxs, mask []int) bool {
+		for i := range idxs {
+			for j := i + 1; j < len(idxs); j++ {
+				offset := (idxs[j] - mask[j]) - (idxs[i] - mask[i])
+				diff := lens[j] - lens[i]
+				if offset < min(0, diff)-width || offset > max(0, diff)+width {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,110 @@
+package mdp
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveWithin is a branch-and-bound variant of SolveWithConfig that may
+// return a slightly suboptimal score faster. the score of aligning a set of
+// prefixes can be no higher than the sum of the optimal pairwise scores of
+// those prefixes, so a move whose bound can improve on the best move found
+// so far by no more than epsilon (in doubled units) is never explored. the
+// error from each pruned move is at most epsilon and does not accumulate
+// along the path, so the returned score is within epsilon (ToNatural of it)
+// of the optimum; upper is the smaller of score plus that slack and the
+// pairwise bound of the whole input. epsilon 0 gives the exact optimum.
+// pruning needs the Pairwise gap model without a GapGap reward, see
+// pairwiseBounded; otherwise the input is solved exactly.
+func SolveWithin(seqStrings []string, cfg bio.ScoreConfig, epsilon int) (score, upper float64) {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	if !pairwiseBounded(cfg) {
+		score = mdp.solve()
+		return score, score
+	}
+	mdp.bound = mdp.pairwiseBound()
+	mdp.epsilon = int64(epsilon)
+	score = mdp.solve()
+	upper = score + bio.ToNatural(int64(epsilon))
+	if b := bio.ToNatural(mdp.bound(mdp.maxIndices())); b < upper {
+		upper = b
+	}
+	return score, upper
+}
+
+// pairwiseBounded reports whether the sum of the optimal pairwise scores
+// bounds the score of a multiple alignment under cfg. it needs the Pairwise
+// gap model, and a GapGap of at most 0: the columns where both sequences of
+// a pair are gapped drop out of its pairwise alignment, so under a GapGap
+// reward they would add to the multiple score beyond the bound.
+func pairwiseBounded(cfg bio.ScoreConfig) bool {
+	return cfg.GapModel == bio.Pairwise && cfg.GapGap <= 0
+}
+
+// pairwiseBound returns the sum over all pairs of sequences of the optimal
+// score of aligning their prefixes ending at idxs, solved in m's mode.
+func (m *multiDP) pairwiseBound() func(idxs []int) int64 {
+	pairs, tables := pairTables(m.seqs, m.cfg, m.global)
+	return func(idxs []int) (sum int64) {
+		for k, p := range pairs {
+			sum = bio.AddScores(sum, tables[k].optimalScore([]int{idxs[p[0]], idxs[p[1]]}))
+		}
+		return
+	}
+}
+
+// ScoreBounds brackets the score SolveWithConfig would return without running
+// the full DP. upper is the sum of the optimal pairwise scores, as in
+// SolveWithin; where that is not a bound, see pairwiseBounded, upper is
+// +Inf. lower is the score the Solve objective gives the path of a
+// quick progressive alignment (msa.Progressive). Both take time quadratic in
+// the sequence lengths per pair rather than the product of all the lengths.
+func ScoreBounds(seqStrings []string, cfg bio.ScoreConfig) (lower, upper float64) {
+	if len(seqStrings) < 2 {
+		return 0, 0
+	}
+	// only the pairwise tables are needed, not the full one
+	m := &multiDP{seqs: cfg.Sequences(seqStrings), cfg: cfg, unit: 1}
+	upper = math.Inf(1)
+	if pairwiseBounded(cfg) {
+		upper = bio.ToNatural(m.pairwiseBound()(m.maxIndices()))
+	}
+	a, _ := msa.Progressive(seqStrings, cfg)
+	return bio.ToNatural(m.pathScore(a)), upper
+}
+
+// pathScore returns the score of the fixed alignment a under m's clamped
+// objective: walking its columns, the running score restarts at zero on
+// every base case cell and is floored at zero, as optimalScore does, so it
+// is never above optimalScore at the final cell.
+func (m *multiDP) pathScore(a *msa.Alignment) (score int64) {
+	idxs := make([]int, len(a.Rows))
+	for k := 0; k < a.Width(); k++ {
+		col := a.Column(k)
+		if m.isBaseCase(idxs) {
+			score = 0
+		}
+		for i, b := range col {
+			if b != bio.X {
+				idxs[i]++
+			}
+		}
+		if allGaps(col) {
+			continue
+		}
+		score = bio.MaxScore(0, bio.AddScores(score, m.score(col)))
+	}
+	return
+}
+
+// allGaps reports whether col holds no bases
+func allGaps(col []bio.Base) bool {
+	for _, b := range col {
+		if b != bio.X {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package mdp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// ErrNoSequences is returned by SolveChecked, SolveStranded, SolveFASTA,
+// SolveResult and SolveProgressive for an empty input
+var ErrNoSequences = errors.New("mdp: no sequences to align")
+
+// ErrInvalidCharacter is returned by SolveChecked for a character that is
+// neither a nucleotide, N nor one of bio.GapChars
+var ErrInvalidCharacter = errors.New("mdp: invalid character")
+
+// validChars are the characters AToBase converts exactly. anything else
+// would silently become N, as an IUPAC ambiguity code does, or be skipped,
+// as whitespace is.
+const validChars = "ACGTUNacgtun"
+
+// SolveChecked is like SolveE but also rejects an empty input list with
+// ErrNoSequences and any character outside validChars and bio.GapChars with
+// ErrInvalidCharacter, naming the sequence index and position. lower case
+// is accepted as soft-masking, as it is everywhere else. Solve stays lenient.
+func SolveChecked(seqStrings []string) (float64, error) {
+	if len(seqStrings) == 0 {
+		return 0, ErrNoSequences
+	}
+	for i, s := range seqStrings {
+		for pos, r := range []rune(s) {
+			if !strings.ContainsRune(validChars, r) && !bio.IsGap(r) {
+				return 0, fmt.Errorf("%w %q in sequence %v at position %v", ErrInvalidCharacter, r, i, pos)
+			}
+		}
+	}
+	return SolveE(seqStrings)
+}
@@ -0,0 +1,26 @@
+package mdp
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveCodons is like SolveAlignment for protein-coding sequences: every
+// move of the DP places a whole codon of a sequence, or three gaps, so gaps
+// come in multiples of three and no sequence's reading frame is broken. the
+// three columns of a move are scored at the nucleotide level under cfg;
+// scoring translated codons would need a genetic code and protein scores,
+// which the package does not have. every sequence must be in frame, its
+// length a multiple of three.
+func SolveCodons(seqStrings []string, cfg bio.ScoreConfig) (*msa.Alignment, error) {
+	seqs := bio.AsToSeqs(seqStrings)
+	for i, seq := range seqs {
+		if len(seq.Bases)%3 != 0 {
+			return nil, fmt.Errorf("mdp: sequence %v has length %v, not a whole number of codons", i, len(seq.Bases))
+		}
+	}
+	mdp := newUnitDP(seqs, cfg, 3)
+	return mdp.alignment(nil), nil
+}
@@ -0,0 +1,51 @@
+package mdp
+
+import (
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveAlignmentCompact is like SolveAlignment but among the co-optimal
+// alignments it returns one with the fewest columns, leading unscored
+// columns included, so gaps are only opened where the score needs them.
+// ties are broken first by score, then by width, and only then by taking
+// the first mask in subsetMasks order, so the result is deterministic.
+func SolveAlignmentCompact(seqStrings []string, cfg bio.ScoreConfig) *msa.Alignment {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	mdp.lengths = newStore(mdp.dims())
+	return mdp.alignment(nil)
+}
+
+// compactMove returns the first of the co-optimal masks at idxs that leads
+// to the shortest alignment
+func (m *multiDP) compactMove(idxs []int, ties [][]int) []int {
+	best, shortest := ties[0], -1
+	for _, mask := range ties {
+		mIdxs, _ := maskedIdxs(idxs, mask)
+		if l := m.compactLength(mIdxs); shortest < 0 || l < shortest {
+			best, shortest = mask, l
+		}
+	}
+	return best
+}
+
+// compactLength returns the fewest columns of any co-optimal path ending at
+// idxs, counting the leading columns its unscored prefixes need where it
+// starts, as traceback would lay them out.
+func (m *multiDP) compactLength(idxs []int) int {
+	if v := m.lengths.At(idxs); v > 0 {
+		return int(v - 1)
+	}
+	// the path starts here
+	length := bio.Max(append([]int{0}, idxs...)...) * m.unit
+	if !m.isBaseCase(idxs) {
+		for i, mask := range m.OptimalMoves(idxs) {
+			mIdxs, _ := maskedIdxs(idxs, mask)
+			if l := m.unit + m.compactLength(mIdxs); i == 0 || l < length {
+				length = l
+			}
+		}
+	}
+	m.lengths.Set(int64(length+1), idxs)
+	return length
+}
@@ -0,0 +1,124 @@
+package mdp
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveConsistency aligns the sequences with a T-Coffee style consistency
+// objective instead of plain sum-of-pairs.
+//
+// The primary library comes from AllPairwise: every residue pair (p, q) that
+// the optimal global alignment of sequences i and j puts in one column gets
+// that alignment's percent identity as its weight. The alignments are global
+// so that only residues the pairwise score pairs enter the library, not the
+// unscored leading columns of a clamped alignment. The library is then extended
+// through every third sequence k: the pair (p, q) also gains
+// min(w_ik(p, r), w_kj(r, q)) for each residue r of k that the pairwise
+// alignments align to both p and q, so residue pairs supported transitively
+// weigh more. Each residue has at most one partner per pairwise alignment,
+// so the extension costs O(n^3 L) for n sequences of length L, on top of the
+// O(n^2 L^2) pairwise alignments.
+//
+// The alignment then maximises the sum of the extended weights of the
+// residue pairs sharing a column, gaps scoring 0, solved exactly and
+// globally. The returned float is that objective, in library units; the
+// alignment's Score is its sum-of-pairs score under cfg.
+func SolveConsistency(seqStrings []string, cfg bio.ScoreConfig) (*msa.Alignment, float64) {
+	seqs := cfg.Sequences(seqStrings)
+	lib := extendLibrary(primaryLibrary(seqStrings, cfg))
+	mdp := newMultiDP(seqs, cfg)
+	mdp.global = true
+	mdp.library = func(idxs, mask []int) (sum int64) {
+		for i := range idxs {
+			for j := i + 1; j < len(idxs); j++ {
+				if mask[i] == 1 && mask[j] == 1 {
+					sum += int64(lib[i][j][[2]int{idxs[i] - 1, idxs[j] - 1}])
+				}
+			}
+		}
+		return
+	}
+	a := mdp.alignment(nil)
+	// library weights are not doubled, so the table holds the objective as is
+	objective := float64(mdp.optimalScore(mdp.maxIndices()))
+	a.Rescore(cfg)
+	return a, objective
+}
+
+// pairLibrary holds the pairwise alignments of a library: match[i][j][p] is
+// the residue of sequence j aligned to residue p of sequence i, or -1, and
+// weight[i][j] the weight of those pairs. both orders of a pair are filled.
+type pairLibrary struct {
+	match  [][][]int
+	weight [][]int
+}
+
+// primaryLibrary builds the library of the optimal global pairwise alignments,
+// weighted by their percent identity
+func primaryLibrary(seqStrings []string, cfg bio.ScoreConfig) *pairLibrary {
+	n := len(seqStrings)
+	lib := &pairLibrary{match: make([][][]int, n), weight: make([][]int, n)}
+	for i := range lib.match {
+		lib.match[i] = make([][]int, n)
+		lib.weight[i] = make([]int, n)
+	}
+	for _, r := range AllPairwise(seqStrings, cfg) {
+		w := int(math.Round(r.Alignment.PercentIdentity(0, 1, msa.ExcludeGaps)))
+		lib.weight[r.I][r.J], lib.weight[r.J][r.I] = w, w
+		positions := r.Alignment.AnnotatedRows()
+		lib.match[r.I][r.J] = partners(positions[0], positions[1])
+		lib.match[r.J][r.I] = partners(positions[1], positions[0])
+	}
+	return lib
+}
+
+// partners maps each residue of one row to the residue of the other row in
+// its column, or -1
+func partners(from, to []int) (match []int) {
+	for c, p := range from {
+		if p >= 0 {
+			match = append(match, to[c])
+		}
+	}
+	return
+}
+
+// extendLibrary returns the extended weight of every residue pair, keyed by
+// [p, q], for each pair of sequences i < j
+func extendLibrary(lib *pairLibrary) [][]map[[2]int]int {
+	n := len(lib.match)
+	ext := make([][]map[[2]int]int, n)
+	for i := range ext {
+		ext[i] = make([]map[[2]int]int, n)
+		for j := i + 1; j < n; j++ {
+			pairs := map[[2]int]int{}
+			for p, q := range lib.match[i][j] {
+				if q >= 0 {
+					pairs[[2]int{p, q}] += lib.weight[i][j]
+				}
+			}
+			for k := 0; k < n; k++ {
+				if k == i || k == j {
+					continue
+				}
+				w := lib.weight[i][k]
+				if lib.weight[k][j] < w {
+					w = lib.weight[k][j]
+				}
+				for p, r := range lib.match[i][k] {
+					if r < 0 {
+						continue
+					}
+					if q := lib.match[k][j][r]; q >= 0 {
+						pairs[[2]int{p, q}] += w
+					}
+				}
+			}
+			ext[i][j] = pairs
+		}
+	}
+	return ext
+}
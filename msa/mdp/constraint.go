@@ -0,0 +1,114 @@
+package mdp
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveConstrained aligns the sequences globally, as SolveGlobal does, while
+// keeping a trusted guide alignment of some of them: every guide row must
+// spell (without its gaps) one of the inputs, each input used at most once,
+// and in the result those rows, with the columns where they are all gapped
+// dropped, are exactly the guide with its own all-gap columns dropped. the
+// other sequences are placed freely around them. an error is returned if a
+// guide row is not an unused input, or for more than MaxSequences inputs.
+func SolveConstrained(seqStrings []string, guide *msa.Alignment, cfg bio.ScoreConfig) (*msa.Alignment, error) {
+	if len(seqStrings) > MaxSequences {
+		return nil, fmt.Errorf("%w: %v sequences, MaxSequences is %v", ErrTooManySequences, len(seqStrings), MaxSequences)
+	}
+	seqs := cfg.Sequences(seqStrings)
+	rows, err := guideRows(seqs, guide)
+	if err != nil {
+		return nil, err
+	}
+	mdp := newMultiDP(seqs, cfg)
+	mdp.global = true
+	mdp.allowed = guidedMoves(guide, rows)
+	return mdp.alignment(nil), nil
+}
+
+// guideRows returns, for every row of guide, the index of the input it
+// spells
+func guideRows(seqs []*bio.Sequence, guide *msa.Alignment) ([]int, error) {
+	used := make([]bool, len(seqs))
+	rows := make([]int, len(guide.Rows))
+	for g, row := range guide.Rows {
+		var residues []bio.Base
+		for _, b := range row.Bases {
+			if b != bio.X {
+				residues = append(residues, b)
+			}
+		}
+		rows[g] = -1
+		for i, seq := range seqs {
+			if !used[i] && slices.Equal(seq.Bases, residues) {
+				rows[g], used[i] = i, true
+				break
+			}
+		}
+		if rows[g] < 0 {
+			return nil, fmt.Errorf("mdp: guide row %v is not one of the remaining input sequences", g)
+		}
+	}
+	return rows, nil
+}
+
+// guidedMoves returns the move filter for a guide whose row g is input
+// rows[g]. the guide's columns trace a path through the indices of those
+// inputs; a cell is only entered on that path, so a move must either leave
+// the guided inputs where they are or take exactly the guide column that
+// ends at the cell's guided indices.
+func guidedMoves(guide *msa.Alignment, rows []int) func(idxs, mask []int) bool {
+	// the guide column mask ending at each point of the path
+	ending := map[string][]int{}
+	point := make([]int, len(rows))
+	for c := 0; c < guide.Width(); c++ {
+		step := make([]int, len(rows))
+		moved := false
+		for g, b := range guide.Column(c) {
+			if b != bio.X {
+				step[g], moved = 1, true
+				point[g]++
+			}
+		}
+		if moved {
+			ending[tupleKey(point)] = step
+		}
+	}
+	guided := make([]int, len(rows))
+	return func(idxs, mask []int) bool {
+		still := true
+		for g, i := range rows {
+			guided[g] = idxs[i]
+			still = still && mask[i] == 0
+		}
+		if still {
+			return true
+		}
+		step, ok := ending[tupleKey(guided)]
+		if !ok {
+			return false
+		}
+		for g, i := range rows {
+			if mask[i] != step[g] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// tupleKey renders an index tuple as a map key
+func tupleKey(idxs []int) string {
+	var sb strings.Builder
+	for _, i := range idxs {
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package mdp
+
+import (
+	"context"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// cancelCheckInterval is the number of cells computed between checks of the
+// context, see SolveContext
+const cancelCheckInterval = 1024
+
+// SolveContext is like Solve but returns ctx.Err() once ctx is cancelled or
+// its deadline passes. the context is checked every cancelCheckInterval
+// cells, so a cancelled solve stops promptly but not immediately. a cell is
+// only cached once fully computed, so an interrupted table holds no partial
+// scores.
+func SolveContext(ctx context.Context, seqStrings []string) (float64, error) {
+	mdp := newMultiDP(bio.AsToSeqs(seqStrings), bio.DefaultScoreConfig())
+	mdp.ctx = ctx
+	score := mdp.solve()
+	if mdp.err != nil {
+		return 0, mdp.err
+	}
+	return score, nil
+}
+
+// SolveAlignmentContext is like SolveAlignment but returns ctx.Err() once
+// ctx is done, checking it as SolveContext does, and first rejects inputs
+// beyond MaxSequences or MaxWork with ErrTooManySequences or ErrInfeasible.
+// unlike SolveAlignmentE it solves the sequences as given, gaps included.
+func SolveAlignmentContext(ctx context.Context, seqStrings []string, cfg bio.ScoreConfig) (*msa.Alignment, error) {
+	if err := checkLimits(seqStrings); err != nil {
+		return nil, err
+	}
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	mdp.ctx = ctx
+	if mdp.solve(); mdp.err != nil {
+		return nil, mdp.err
+	}
+	// every cell the traceback reads is cached, so it can't be cut short
+	return mdp.alignment(nil), nil
+}
+
+// cancelled reports whether m's context has been cancelled, checking it
+// every cancelCheckInterval calls and remembering the error in m.err
+func (m *multiDP) cancelled() bool {
+	if m.err != nil {
+		return true
+	}
+	if m.calls++; m.calls%cancelCheckInterval == 0 {
+		m.err = m.ctx.Err()
+	}
+	return m.err != nil
+}
@@ -0,0 +1,75 @@
+package mdp
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/nd"
+)
+
+// SolveDebug solves the sequences under cfg like SolveWithConfig and also
+// returns the tables described at DebugTables, e.g. to draw the DP matrix of
+// two sequences with an arrow per cell.
+func SolveDebug(seqStrings []string, cfg bio.ScoreConfig) (score float64, scores, moves *nd.Array) {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	score = mdp.solve()
+	scores, moves = mdp.DebugTables()
+	return
+}
+
+// SolveObserved solves the sequences under cfg like SolveWithConfig and calls
+// onCell with the index tuple and doubled score of each cell as its score is
+// final, in the order the recursion completes them, so every cell is
+// reported after the cells it was computed from. indices are offset by one
+// as in DebugTables and base cases are not reported. onCell must not keep or
+// modify idxs. the score is the same as without it.
+func SolveObserved(seqStrings []string, cfg bio.ScoreConfig, onCell func(idxs []int, score int64)) float64 {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	mdp.onCell = onCell
+	return mdp.solve()
+}
+
+// DebugTables returns the filled score table, in doubled units, and a table
+// of the move chosen at each cell. both have a dimension of len+1 per
+// sequence, index i of a sequence meaning its first i bases are aligned, so
+// index 0 is the row or column of the empty prefix. a move is the first mask
+// of OptimalMoves, the one traceback takes, encoded with bit i set when
+// sequence i contributes a base.
+// cells that are base cases, were never computed or were floored at zero
+// with no move reaching them hold 0 in moves. the move table is built on
+// demand from the solved scores, so solving without it costs no memory.
+// the scores are copied out of the int64 table, see debugInt.
+func (m *multiDP) DebugTables() (scores, moves *nd.Array) {
+	moves, scores = nd.NewArray(m.dims()), nd.NewArray(m.dims())
+	m.eachCell(func(idxs []int) {
+		if m.isBaseCase(idxs) || m.cached.At(idxs) != 1 {
+			return
+		}
+		scores.Set(debugInt(m.table.At(idxs)), idxs)
+		if ties := m.OptimalMoves(idxs); len(ties) > 0 {
+			moves.Set(encodeMask(ties[0]), idxs)
+		}
+	})
+	return scores, moves
+}
+
+// debugInt converts a score to the int of an nd.Array, clamping it to the
+// range of int, which only a 32-bit build can exceed; Forbidden becomes
+// math.MinInt
+func debugInt(score int64) int {
+	switch {
+	case score < math.MinInt:
+		return math.MinInt
+	case score > math.MaxInt:
+		return math.MaxInt
+	}
+	return int(score)
+}
+
+// encodeMask packs a mask into an int, bit i holding mask[i]
+func encodeMask(mask []int) (code int) {
+	for i, bit := range mask {
+		code |= bit << i
+	}
+	return
+}
@@ -0,0 +1,89 @@
+package mdp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// ErrEmptySequence is returned for an input sequence without residues, one
+// that is empty or holds only gap characters.
+var ErrEmptySequence = errors.New("mdp: empty sequence")
+
+// EmptyPolicy selects what the checked entry points (SolveE, SolveAlignmentE
+// and the FASTA solvers) do with sequences without residues. the other
+// entry points solve their input as given; there an empty sequence touches
+// the boundary from the start, so under the clamped objective the whole
+// input scores 0.
+type EmptyPolicy int
+
+const (
+	// RejectEmpty fails with ErrEmptySequence naming the first empty input.
+	RejectEmpty EmptyPolicy = iota
+	// SkipEmpty solves the other sequences and gives every empty one a row
+	// of gaps at its input position. the gap rows are not scored, so Score
+	// is that of the other sequences alone.
+	SkipEmpty
+)
+
+// EmptySequences is the policy the checked entry points apply
+var EmptySequences = RejectEmpty
+
+// checkSequences drops the gap characters from every sequence, so an already
+// aligned row is solved from its residues, applies EmptySequences to those
+// left without residues and checks MaxSequences and MaxWork against the
+// others. whitespace is dropped too, as parsing would skip it, so a
+// sequence of blanks and line breaks counts as empty. kept
+// holds the remaining sequences in order and empty the input indices of the
+// skipped ones.
+func checkSequences(seqStrings []string) (kept []string, empty []int, err error) {
+	for i, s := range seqStrings {
+		residues := strings.Map(func(r rune) rune {
+			if bio.IsGap(r) || unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, s)
+		switch {
+		case residues != "":
+			kept = append(kept, residues)
+		case EmptySequences == SkipEmpty:
+			empty = append(empty, i)
+		default:
+			return nil, nil, fmt.Errorf("%w: sequence %v has no residues", ErrEmptySequence, i)
+		}
+	}
+	if err := checkLimits(kept); err != nil {
+		return nil, nil, err
+	}
+	return kept, empty, nil
+}
+
+// withGapRows returns a with a row of gaps inserted at each index of empty,
+// which must be increasing, so its rows line up with the input again
+func withGapRows(a *msa.Alignment, empty []int) *msa.Alignment {
+	if len(empty) == 0 {
+		return a
+	}
+	rows := make([]*bio.Sequence, 0, len(a.Rows)+len(empty))
+	next := 0
+	for len(rows) < cap(rows) {
+		if len(empty) > 0 && empty[0] == len(rows) {
+			gaps := bio.NewSequence()
+			for i := 0; i < a.Width(); i++ {
+				gaps.Bases = append(gaps.Bases, bio.X)
+			}
+			rows = append(rows, gaps)
+			empty = empty[1:]
+			continue
+		}
+		rows = append(rows, a.Rows[next])
+		next++
+	}
+	a.Rows = rows
+	return a
+}
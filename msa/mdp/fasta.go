@@ -0,0 +1,132 @@
+package mdp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveFASTA reads FASTA records from r and returns the score of aligning
+// all of their sequences, as SolveE does. it wraps ErrNoSequences if r holds
+// no records.
+func SolveFASTA(r io.Reader) (float64, error) {
+	records, err := bio.ReadFASTA(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, fmt.Errorf("%w: no FASTA records", ErrNoSequences)
+	}
+	var seqStrings []string
+	for _, rec := range records {
+		seqStrings = append(seqStrings, rec.Seq)
+	}
+	return SolveE(seqStrings)
+}
+
+// SolveFASTAPair reads FASTA records from r and aligns the two records with
+// ids idA and idB under cfg, as SolveAlignmentE does. it is an error for
+// either id to be missing or to appear more than once.
+func SolveFASTAPair(r io.Reader, idA, idB string, cfg bio.ScoreConfig) (*msa.Alignment, error) {
+	records, err := bio.ReadFASTA(r)
+	if err != nil {
+		return nil, err
+	}
+	var seqStrings []string
+	for _, id := range []string{idA, idB} {
+		var found []string
+		for _, rec := range records {
+			if rec.ID == id {
+				found = append(found, rec.Seq)
+			}
+		}
+		switch len(found) {
+		case 0:
+			return nil, fmt.Errorf("mdp: no FASTA record with id %q", id)
+		case 1:
+			seqStrings = append(seqStrings, found[0])
+		default:
+			return nil, fmt.Errorf("mdp: %v FASTA records with id %q", len(found), id)
+		}
+	}
+	a, err := SolveAlignmentE(seqStrings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.IDs = []string{idA, idB}
+	return a, nil
+}
+
+// SolveFASTAGroups reads FASTA records from r, partitions them by the key
+// groupBy returns for each record ID and aligns every group independently
+// under cfg, as SolveAlignmentE does, keeping IDs in input order. groups are
+// solved in parallel by up to GOMAXPROCS workers. a group that cannot be
+// solved, such as one over MaxSequences, is left out of the map and its
+// error is joined into the returned error, so the other groups are still
+// returned.
+func SolveFASTAGroups(r io.Reader, groupBy func(id string) string, cfg bio.ScoreConfig) (map[string]*msa.Alignment, error) {
+	records, err := bio.ReadFASTA(r)
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string][]bio.FASTARecord{}
+	var keys []string
+	for _, rec := range records {
+		key := groupBy(rec.ID)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], rec)
+	}
+	sort.Strings(keys)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	results := map[string]*msa.Alignment{}
+	work := make(chan string)
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				a, err := solveGroup(groups[key], cfg)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("mdp: group %q: %w", key, err))
+				} else {
+					results[key] = a
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, key := range keys {
+		work <- key
+	}
+	close(work)
+	wg.Wait()
+	// workers finish in any order, keep the joined error deterministic
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return results, errors.Join(errs...)
+}
+
+func solveGroup(records []bio.FASTARecord, cfg bio.ScoreConfig) (*msa.Alignment, error) {
+	var ids, seqStrings []string
+	for _, rec := range records {
+		ids = append(ids, rec.ID)
+		seqStrings = append(seqStrings, rec.Seq)
+	}
+	a, err := SolveAlignmentE(seqStrings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.IDs = ids
+	return a, nil
+}
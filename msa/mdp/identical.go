@@ -0,0 +1,34 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// identicalScore returns the doubled score of seqs in closed form when they
+// are all the same sequence of concrete bases: the ungapped stack, scoring
+// pairs × Match × length. The stack is only known to be optimal under
+// Pairwise with a non-negative Match that no mismatch or pair of gaps beats
+// and a GapGap that rewards nothing, and with no matrix set, since the score then splits into pairwise alignments of a sequence with
+// itself; ok is false otherwise and the DP must be run.
+func identicalScore(seqs []*bio.Sequence, cfg bio.ScoreConfig) (score int64, ok bool) {
+	if len(seqs) == 0 || cfg.GapModel != bio.Pairwise || cfg.Matrix() != nil ||
+		cfg.Match < 0 || cfg.Match < cfg.Mismatch || cfg.Match < 2*cfg.Gap || cfg.GapGap > 0 {
+		return 0, false
+	}
+	first := seqs[0].Bases
+	for _, b := range first {
+		if b < bio.A || b > bio.T || cfg.PairScore(b, b) != int64(cfg.Match) {
+			return 0, false
+		}
+	}
+	for _, seq := range seqs[1:] {
+		if len(seq.Bases) != len(first) {
+			return 0, false
+		}
+		for i, b := range seq.Bases {
+			if b != first[i] {
+				return 0, false
+			}
+		}
+	}
+	pairs := len(seqs) * (len(seqs) - 1) / 2
+	return bio.MulScores(int64(pairs), bio.MulScores(int64(cfg.Match), int64(len(first)))), true
+}
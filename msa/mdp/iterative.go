@@ -0,0 +1,88 @@
+package mdp
+
+import (
+	"sort"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// FillOrder is the order in which SolveIterative visits the table's cells.
+// every order visits a cell after all of its predecessors, so the score does
+// not depend on it; only the memory access pattern does.
+type FillOrder int
+
+// RowMajor ... enum represents a fill order
+const (
+	// RowMajor visits cells in lexicographic order of their indices, the
+	// layout order of the nd.Array.
+	RowMajor FillOrder = iota
+	// AntiDiagonal visits cells by increasing sum of their indices, row-major
+	// within each anti-diagonal.
+	AntiDiagonal
+	// Morton visits cells along the Z-order curve, interleaving the bits of
+	// the indices with the first sequence's bit most significant.
+	Morton
+)
+
+// SolveIterative is like SolveWithConfig but fills the whole table bottom-up
+// in order rather than recursing from the final cell. Every cell is computed,
+// including those the recursive solver never reaches.
+func SolveIterative(seqStrings []string, cfg bio.ScoreConfig, order FillOrder) float64 {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	mdp.fill(order)
+	return mdp.solve()
+}
+
+// fill computes every cell in order. the predecessors of a cell are cached by
+// the time it is visited, so optimalScore never recurses more than one level.
+func (m *multiDP) fill(order FillOrder) {
+	if order == RowMajor {
+		m.eachCell(func(idxs []int) {
+			m.optimalScore(idxs)
+		})
+		return
+	}
+	var cells [][]int
+	m.eachCell(func(idxs []int) {
+		cells = append(cells, cpy(idxs))
+	})
+	less := mortonLess
+	if order == AntiDiagonal {
+		less = diagonalLess
+	}
+	// eachCell is row-major, so a stable sort keeps that order within ties
+	sort.SliceStable(cells, func(i, j int) bool {
+		return less(cells[i], cells[j])
+	})
+	for _, idxs := range cells {
+		m.optimalScore(idxs)
+	}
+}
+
+// diagonalLess orders index tuples by the sum of their indices
+func diagonalLess(a, b []int) bool {
+	sa, sb := 0, 0
+	for i := range a {
+		sa += a[i]
+		sb += b[i]
+	}
+	return sa < sb
+}
+
+// mortonLess orders index tuples along the Z-order curve without computing
+// the interleaved codes: the dimension whose indices differ in the highest
+// bit decides.
+func mortonLess(a, b []int) bool {
+	dim := 0
+	for i := 1; i < len(a); i++ {
+		if msbLess(a[dim]^b[dim], a[i]^b[i]) {
+			dim = i
+		}
+	}
+	return a[dim] < b[dim]
+}
+
+// msbLess reports whether the highest set bit of x is below that of y
+func msbLess(x, y int) bool {
+	return x < y && x < x^y
+}
@@ -0,0 +1,73 @@
+package mdp
+
+import (
+	"fmt"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// LengthPolicy controls how sequences of differing lengths are handled
+// before alignment.
+type LengthPolicy int
+
+const (
+	// Strict aligns the sequences exactly as given.
+	Strict LengthPolicy = iota
+	// PadShorter appends trailing gaps to every sequence shorter than the
+	// longest one so that all inputs have the same length.
+	PadShorter
+	// Error rejects the input if the longest sequence is more than maxRatio
+	// times the length of any other sequence.
+	Error
+)
+
+// LengthRatioError is returned under the Error policy. Indices holds the
+// positions (in input order) of the sequences that are too short.
+type LengthRatioError struct {
+	Indices  []int
+	MaxRatio float64
+}
+
+func (e *LengthRatioError) Error() string {
+	return fmt.Sprintf("mdp: sequences %v are more than %v times shorter than the longest sequence",
+		e.Indices, e.MaxRatio)
+}
+
+// SolveWithLengthPolicy applies policy to the sequences and then solves them
+// like Solve. maxRatio is only used by the Error policy.
+func SolveWithLengthPolicy(seqStrings []string, policy LengthPolicy, maxRatio float64) (float64, error) {
+	seqStrings, err := ApplyLengthPolicy(seqStrings, policy, maxRatio)
+	if err != nil {
+		return 0, err
+	}
+	return Solve(seqStrings), nil
+}
+
+// ApplyLengthPolicy returns seqStrings after policy: padded under
+// PadShorter, checked under Error and as given under Strict
+func ApplyLengthPolicy(seqStrings []string, policy LengthPolicy, maxRatio float64) ([]string, error) {
+	longest := 0
+	for _, s := range seqStrings {
+		longest = bio.Max(longest, len(s))
+	}
+	switch policy {
+	case PadShorter:
+		padded := make([]string, len(seqStrings))
+		for i, s := range seqStrings {
+			padded[i] = s + strings.Repeat("-", longest-len(s))
+		}
+		return padded, nil
+	case Error:
+		var short []int
+		for i, s := range seqStrings {
+			if float64(len(s))*maxRatio < float64(longest) {
+				short = append(short, i)
+			}
+		}
+		if len(short) > 0 {
+			return nil, &LengthRatioError{Indices: short, MaxRatio: maxRatio}
+		}
+	}
+	return seqStrings, nil
+}
@@ -0,0 +1,79 @@
+package mdp
+
+import (
+	"errors"
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// MaxSequences is the largest number of sequences SolveE will attempt.
+// time and memory grow exponentially with the sequence count (2^n - 1 masks
+// per cell over a table the size of the product of the lengths), so inputs
+// above this cap are rejected rather than left to run out of memory. raise
+// it explicitly if you know the input is small enough.
+var MaxSequences = 5
+
+// ErrTooManySequences is returned when the input exceeds MaxSequences.
+var ErrTooManySequences = errors.New("mdp: too many sequences for exact alignment")
+
+// MaxWork is the largest estimated amount of work, table cells times the
+// 2^n - 1 moves evaluated per cell, that the checked entry points will
+// attempt. the default allows about a billion move evaluations, seconds to
+// minutes of work; raise it explicitly for larger inputs.
+var MaxWork = int64(1) << 30
+
+// ErrInfeasible is returned when the estimated work exceeds MaxWork
+var ErrInfeasible = errors.New("mdp: input too large for exact alignment")
+
+// estimatedWork returns the number of cells of the table for seqStrings
+// times the number of moves per cell, stopping once it exceeds MaxWork
+func estimatedWork(seqStrings []string) int64 {
+	work := int64(1)<<len(seqStrings) - 1
+	for _, s := range seqStrings {
+		if work > MaxWork {
+			break
+		}
+		work *= int64(len(s) + 1)
+	}
+	return work
+}
+
+// checkLimits returns ErrTooManySequences or ErrInfeasible if seqStrings
+// exceed MaxSequences or MaxWork
+func checkLimits(seqStrings []string) error {
+	if len(seqStrings) > MaxSequences {
+		return fmt.Errorf("%w: %v sequences, MaxSequences is %v", ErrTooManySequences, len(seqStrings), MaxSequences)
+	}
+	if work := estimatedWork(seqStrings); work > MaxWork {
+		return fmt.Errorf("%w: at least %v cell moves for %v sequences, MaxWork is %v", ErrInfeasible, work, len(seqStrings), MaxWork)
+	}
+	return nil
+}
+
+// SolveE is like Solve but checks its input first: it returns
+// ErrTooManySequences or ErrInfeasible instead of attempting an infeasible
+// input, and
+// applies EmptySequences to sequences without residues. gap characters in
+// the input are ignored.
+func SolveE(seqStrings []string) (float64, error) {
+	kept, _, err := checkSequences(seqStrings)
+	if err != nil || len(kept) == 0 {
+		return 0, err
+	}
+	return Solve(kept), nil
+}
+
+// SolveAlignmentE is like SolveAlignment with the checks of SolveE. rows of
+// sequences skipped under SkipEmpty are all gaps.
+func SolveAlignmentE(seqStrings []string, cfg bio.ScoreConfig) (*msa.Alignment, error) {
+	kept, empty, err := checkSequences(seqStrings)
+	if err != nil {
+		return nil, err
+	}
+	if len(kept) == 0 {
+		return withGapRows(&msa.Alignment{Exact: true}, empty), nil
+	}
+	return withGapRows(SolveAlignment(kept, cfg), empty), nil
+}
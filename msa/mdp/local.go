@@ -0,0 +1,37 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// SolveLocal returns the score of the best local alignment, in the manner of
+// Smith-Waterman, together with the half-open range [start, end) of each
+// sequence that it aligns. the clamped objective of Solve already floors
+// partial scores at zero, so a cell's score is that of the best block ending
+// there and the local score is the maximum over all cells. ties go to the
+// first cell in row-major order. if no block scores above zero the score is
+// 0 and every range is empty.
+func SolveLocal(seqStrings []string) (float64, [][2]int) {
+	return SolveLocalWithConfig(seqStrings, bio.DefaultScoreConfig())
+}
+
+// SolveLocalWithConfig is like SolveLocal but scores columns with cfg
+func SolveLocalWithConfig(seqStrings []string, cfg bio.ScoreConfig) (float64, [][2]int) {
+	m := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	best, end := int64(0), make([]int, len(m.seqs))
+	m.eachCell(func(idxs []int) {
+		if s := m.optimalScore(idxs); s > best {
+			best = s
+			copy(end, idxs)
+		}
+	})
+	// walk back while the block still scores, so it starts where the score
+	// last left zero
+	start := end
+	for !m.isBaseCase(start) && m.optimalScore(start) > 0 {
+		start, _ = maskedIdxs(start, m.OptimalMoves(start)[0])
+	}
+	ranges := make([][2]int, len(end))
+	for i := range ranges {
+		ranges[i] = [2]int{start[i], end[i]}
+	}
+	return bio.ToNatural(best), ranges
+}
@@ -0,0 +1,33 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// SolveWithMatrix is like SolveProtein but scores each pair of residues in a
+// column with score, for example a substitution matrix read by
+// bio.ReadMatrix, in place of Match and Mismatch. a residue against a gap
+// scores gap and a pair of gaps 0, as under the Pairwise gap model. score
+// and gap are doubled like the other scores.
+func SolveWithMatrix(seqStrings []string, score func(a, b bio.Base) int, gap int) float64 {
+	cfg := bio.DefaultScoreConfig()
+	cfg.Gap = gap
+	mdp := newMultiDP(bio.AsToProteins(seqStrings), cfg)
+	mdp.matrix = score
+	return mdp.solve()
+}
+
+// matrixScore is the column score under m.matrix
+func (m *multiDP) matrixScore(bases []bio.Base) (sum int64) {
+	for i, bi := range bases {
+		for _, bj := range bases[i+1:] {
+			switch {
+			case bi == bio.X && bj == bio.X:
+				sum = bio.AddScores(sum, int64(m.cfg.GapGap))
+			case bi == bio.X || bj == bio.X:
+				sum = bio.AddScores(sum, int64(m.cfg.Gap))
+			default:
+				sum = bio.AddScores(sum, int64(m.matrix(bi, bj)))
+			}
+		}
+	}
+	return
+}
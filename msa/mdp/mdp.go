@@ -1,25 +1,78 @@
 package mdp
 
 import (
+	"context"
+
 	bio "github.com/bsjcho/bioinf"
-	"github.com/bsjcho/nd"
 )
 
 type multiDP struct {
 	seqs   []*bio.Sequence // list of sequences
-	table  *nd.Array       // dp table to store optimal scores
-	cached *nd.Array       // to determine if an optimal score has already been
+	table  store           // dp table to store optimal scores, see newStore
+	cached store           // to determine if an optimal score has already been
 	// calculated. necessary for memoization since scores can be 0
 	subsetMasks [][]int
+	cfg         bio.ScoreConfig
+	global      bool  // charge sequence boundaries and allow negative scores
+	cells       int64 // number of cells computed, see SolveStats
+
+	// when bound is set, moves whose bound can beat best by no more than
+	// epsilon are pruned, see SolveWithin
+	bound   func(idxs []int) int64
+	epsilon int64
+
+	pairs [][2]int // when set, the only pairs scored, see SolveAlignmentPairs
+
+	// residues consumed from a sequence per move, and so the number of
+	// columns a move adds: 1, or 3 when aligning codons, see SolveCodons.
+	// table indices count units rather than residues.
+	unit int
+
+	// when set, traceback prefers the shortest co-optimal alignment and
+	// memoizes compactLength+1 per cell, see SolveAlignmentCompact
+	lengths store
+
+	// when set, the only moves considered from a cell, see SolveConstrained
+	allowed func(idxs, mask []int) bool
+
+	// when set, scores moves in place of the column scores, see
+	// SolveConsistency
+	library func(idxs, mask []int) int64
+
+	// when set, scores pairs of bases in place of cfg, see SolveWithMatrix
+	matrix func(a, b bio.Base) int
+
+	// when set, scores columns in place of cfg, see SolveWithColumnScorer
+	column ColumnScorer
+
+	// when set, per-sequence weights scaled by weightScale, see SolveWeighted
+	weights []int
+
+	// when set, called as each cell is cached, see SolveObserved
+	onCell func(idxs []int, score int64)
+
+	// when set, checked while solving; once it is done err holds its error
+	// and optimalScore returns without caching, see SolveContext
+	ctx   context.Context
+	err   error
+	calls int64
+}
+
+func newMultiDP(s []*bio.Sequence, cfg bio.ScoreConfig) *multiDP {
+	return newUnitDP(s, cfg, 1)
 }
 
-func newMultiDP(s []*bio.Sequence) *multiDP {
-	return &multiDP{
+// newUnitDP is newMultiDP for moves of unit residues
+func newUnitDP(s []*bio.Sequence, cfg bio.ScoreConfig, unit int) *multiDP {
+	m := &multiDP{
 		seqs:        s,
-		table:       nd.NewArray(sizes(s)),
-		cached:      nd.NewArray(sizes(s)),
+		cfg:         cfg,
+		unit:        unit,
 		subsetMasks: generateSubsetMasks(len(s)),
 	}
+	m.table = newStore(m.dims())
+	m.cached = newStore(m.dims())
+	return m
 }
 
 /*
@@ -36,6 +89,13 @@ ie. generateSubsetMasks(3) will return the following column "masks"
 [0 0 1]
 notice that [0 0 0] has been removed from the subset masks so as to
 prevent alignments from containing columns entirely composed of gaps.
+
+the order is part of the contract: it is lexicographic with a base before a
+gap, so of two masks the one placing a base at the first sequence where they
+differ comes first. every traceback without a random source breaks ties by
+taking the first co-optimal mask in this order, which makes the alignments
+deterministic across runs and platforms; it does not depend on map
+iteration or scheduling.
 */
 func generateSubsetMasks(numSeqCompared int) [][]int {
 	x := []int{}
@@ -46,32 +106,116 @@ func generateSubsetMasks(numSeqCompared int) [][]int {
 }
 
 // Solve takes in a list of sequences and returns score of the optimal alignment
+// partial scores are never allowed to drop below zero and any index tuple
+// where a sequence is exhausted scores zero, so leading overhangs are free.
 func Solve(seqStrings []string) float64 {
-	mdp := newMultiDP(bio.AsToSeqs(seqStrings))
+	return SolveWithConfig(seqStrings, bio.DefaultScoreConfig())
+}
+
+// SolveWithConfig is like Solve but scores columns with cfg
+func SolveWithConfig(seqStrings []string, cfg bio.ScoreConfig) float64 {
+	return SolveSequences(cfg.Sequences(seqStrings), cfg)
+}
+
+// SolveProtein is like Solve for sequences of amino acid codes, see
+// bio.Residue. residues score Match against themselves and Mismatch against
+// each other, and gaps are handled as for nucleotides. to solve proteins
+// under another config, convert them with bio.AsToProteins and use
+// SolveSequences.
+func SolveProtein(seqStrings []string) float64 {
+	return SolveSequences(bio.AsToProteins(seqStrings), bio.DefaultScoreConfig())
+}
+
+// SolveIUPAC is like Solve but keeps IUPAC ambiguity codes, see
+// bio.AToIUPAC, so R aligns as a match against A or G. Solve instead reads
+// them as N and scores them Unknown.
+func SolveIUPAC(seqStrings []string) float64 {
+	return SolveSequences(bio.AsToIUPAC(seqStrings), bio.DefaultScoreConfig())
+}
+
+// SolveSequences is like SolveWithConfig for already parsed sequences, so
+// the same sequences can be solved under several configs without reparsing.
+// the sequences are not modified. fewer than two sequences share no pairs
+// and score 0, identical sequences are scored in closed form without running
+// the DP, and pairs by a plain two-row DP.
+func SolveSequences(seqs []*bio.Sequence, cfg bio.ScoreConfig) float64 {
+	if len(seqs) < 2 {
+		return 0
+	}
+	if score, ok := identicalScore(seqs, cfg); ok {
+		return bio.ToNatural(score)
+	}
+	if len(seqs) == 2 && !cfg.TerminalGapFree {
+		return bio.ToNatural((&multiDP{seqs: seqs, cfg: cfg, unit: 1}).twoScore())
+	}
+	mdp := newMultiDP(seqs, cfg)
+	return mdp.solve()
+}
+
+// SolveGlobal returns the score of the optimal global alignment under cfg.
+// unlike Solve, every residue is scored and the result may be negative.
+func SolveGlobal(seqStrings []string, cfg bio.ScoreConfig) float64 {
+	seqs := cfg.Sequences(seqStrings)
+	if len(seqs) < 2 {
+		return 0
+	}
+	if score, ok := identicalScore(seqs, cfg); ok {
+		return bio.ToNatural(score)
+	}
+	if len(seqs) == 2 && !cfg.TerminalGapFree {
+		return bio.ToNatural((&multiDP{seqs: seqs, cfg: cfg, unit: 1, global: true}).twoScore())
+	}
+	mdp := newMultiDP(seqs, cfg)
+	mdp.global = true
 	return mdp.solve()
 }
 
 func (m *multiDP) solve() float64 {
 	optScore := m.optimalScore(m.maxIndices())
 	// values doubled to be able to use integers during calculations
-	// final result is converted to float then divided by two
-	// see const block declared above
-	return float64(optScore) / 2
+	// final result is converted to the natural scale
+	return bio.ToNatural(optScore)
 }
 
 // uses memoization as opposed to tabulation/dp
 // represents optimal score function F(i1, i2, i3, ... , in)
-func (m *multiDP) optimalScore(idxs []int) (best int) {
+func (m *multiDP) optimalScore(idxs []int) (best int64) {
 	// base case
-	for _, i := range idxs {
-		if i <= 0 {
-			return
-		}
+	if m.isBaseCase(idxs) {
+		return
 	}
 	// have we calculated the score for these indices before?
 	if m.cached.At(idxs) == 1 {
 		return m.table.At(idxs)
 	}
+	if m.ctx != nil && m.cancelled() {
+		return
+	}
+	best = m.bestScore(idxs)
+	if m.err != nil {
+		// a predecessor was cancelled, so best is incomplete
+		return
+	}
+	// save results. mark this specific set of indicies as cached.
+	m.table.Set(best, idxs)
+	m.cached.Set(1, idxs)
+	m.cells++
+	if m.onCell != nil {
+		m.onCell(idxs, best)
+	}
+	// fmt.Printf("calced f(%v): %v\n", idxs, best)
+	return
+}
+
+// bestScore returns the best score of a move into idxs, taking the score of
+// each predecessor from optimalScore. when the predecessors are all cached
+// it only reads m, see SolveParallel.
+func (m *multiDP) bestScore(idxs []int) (best int64) {
+	if m.global {
+		// partial scores may be negative so the search can't start at 0.
+		// a cell every move into which is forbidden stays Forbidden
+		best = bio.Forbidden
+	}
 	// see generateSubsetMasks() comment for explaination of subset masks
 	// iterate over all possible masks to find the optimal score
 	for _, mask := range m.subsetMasks {
@@ -82,22 +226,22 @@ func (m *multiDP) optimalScore(idxs []int) (best int) {
 			// because a negative index is invalid and undefined.
 			continue
 		}
+		if m.allowed != nil && !m.allowed(idxs, mask) {
+			continue
+		}
+		// calculate the sum-of-pairs score of the column of bases (and gaps)
+		// given the current indices (idxs) and the mask
+		score := m.moveScore(idxs, mask)
+
+		if m.bound != nil && bio.AddScores(m.bound(mIdxs), score) <= best+m.epsilon {
+			continue
+		}
 		// find optimal score of masked indices
 		optScore := m.optimalScore(mIdxs)
 
-		// maskedBases are the bases (and gaps) given the current indices (idxs)
-		// and the mask.
-		bases := m.maskedBases(idxs, mask)
-		// calculate the score of this column of bases (and gaps) using sum-of-pairs
-		score := bio.ColumnSPScore(bases)
-
 		// maintain best score
-		best = bio.Max(best, optScore+score)
+		best = bio.MaxScore(best, bio.AddScores(optScore, score))
 	}
-	// save results. mark this specific set of indicies as cached.
-	m.table.Set(best, idxs)
-	m.cached.Set(1, idxs)
-	// fmt.Printf("calced f(%v): %v\n", idxs, best)
 	return
 }
 
@@ -105,13 +249,30 @@ func (m *multiDP) optimalScore(idxs []int) (best int) {
 // Helper Functions
 /////////////////////////
 
-func sizes(s []*bio.Sequence) (sizes []int) {
-	for _, seq := range s {
-		sizes = append(sizes, len(seq.Bases)+1)
+// dims returns the table dimensions, one more than the number of units in
+// each sequence
+func (m *multiDP) dims() (sizes []int) {
+	for _, seq := range m.seqs {
+		sizes = append(sizes, len(seq.Bases)/m.unit+1)
 	}
 	return
 }
 
+// in global mode only the origin is a base case. otherwise any index tuple
+// touching a sequence boundary is.
+func (m *multiDP) isBaseCase(idxs []int) bool {
+	zeros := 0
+	for _, i := range idxs {
+		if i <= 0 {
+			zeros++
+		}
+	}
+	if m.global {
+		return zeros == len(idxs)
+	}
+	return zeros > 0
+}
+
 func maskedIdxs(idxs, mask []int) (mIdxs []int, ok bool) {
 	for i, idx := range idxs {
 		x := idx - mask[i]
@@ -123,6 +284,77 @@ func maskedIdxs(idxs, mask []int) (mIdxs []int, ok bool) {
 	return mIdxs, true
 }
 
+// score returns the column score of bases under m's config
+func (m *multiDP) score(bases []bio.Base) int64 {
+	if m.pairs != nil {
+		return m.pairsScore(bases)
+	}
+	if m.matrix != nil {
+		return m.matrixScore(bases)
+	}
+	if m.column != nil {
+		return m.column(bases)
+	}
+	if m.weights != nil {
+		return m.weightedScore(bases)
+	}
+	return bio.ColumnScore(bases, m.cfg)
+}
+
+// moveScore returns the score of the columns added by moving from idxs by
+// mask
+func (m *multiDP) moveScore(idxs, mask []int) int64 {
+	if m.library != nil {
+		return m.library(idxs, mask)
+	}
+	if m.cfg.TerminalGapFree && m.pairs == nil {
+		return m.terminalScore(idxs, mask)
+	}
+	if m.unit == 1 {
+		return m.score(m.maskedBases(idxs, mask))
+	}
+	sum := int64(0)
+	for _, col := range m.moveColumns(idxs, mask) {
+		sum = bio.AddScores(sum, m.score(col))
+	}
+	return sum
+}
+
+// terminalScore is moveScore under cfg.TerminalGapFree. a sequence gapped
+// by the move is still before its first base at index 0 and past its last
+// one at its max index, so the cell alone says which gaps are terminal.
+func (m *multiDP) terminalScore(idxs, mask []int) int64 {
+	last := m.maxIndices()
+	terminal := make([]bool, len(idxs))
+	for i, idx := range idxs {
+		terminal[i] = mask[i] == 0 && (idx == 0 || idx == last[i])
+	}
+	sum := int64(0)
+	for _, col := range m.moveColumns(idxs, mask) {
+		sum = bio.AddScores(sum, bio.TerminalColumnScore(col, terminal, m.cfg))
+	}
+	return sum
+}
+
+// moveColumns returns the unit columns added by moving from idxs by mask
+func (m *multiDP) moveColumns(idxs, mask []int) [][]bio.Base {
+	if m.unit == 1 {
+		return [][]bio.Base{m.maskedBases(idxs, mask)}
+	}
+	cols := make([][]bio.Base, m.unit)
+	for k := range cols {
+		cols[k] = make([]bio.Base, len(idxs))
+		for i, idx := range idxs {
+			if mask[i] == 1 {
+				cols[k][i] = m.seqs[i].Bases[(idx-1)*m.unit+k]
+			} else {
+				cols[k][i] = bio.X
+			}
+		}
+	}
+	return cols
+}
+
 func (m *multiDP) maskedBases(idxs, mask []int) (bases []bio.Base) {
 	for i, idx := range idxs {
 		var b bio.Base
@@ -137,7 +369,7 @@ func (m *multiDP) maskedBases(idxs, mask []int) (bases []bio.Base) {
 }
 
 func (m *multiDP) maxIndices() (indices []int) {
-	for _, size := range sizes(m.seqs) {
+	for _, size := range m.dims() {
 		indices = append(indices, size-1)
 	}
 	return
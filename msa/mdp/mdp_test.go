@@ -1,8 +1,19 @@
 package mdp
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
 )
 
 const (
@@ -48,3 +59,1467 @@ func TestMDPSimple(t *testing.T) {
 		t.Error("Incorrect score.")
 	}
 }
+
+func TestLengthPolicy(t *testing.T) {
+	seqs := []string{x1, "AAT", x3}
+	_, err := SolveWithLengthPolicy(seqs, Error, 2)
+	lerr, ok := err.(*LengthRatioError)
+	if !ok || len(lerr.Indices) != 1 || lerr.Indices[0] != 1 {
+		t.Errorf("expected sequence 1 to be reported, got %v", err)
+	}
+	if _, err := SolveWithLengthPolicy(seqs, Error, 4); err != nil {
+		t.Error(err)
+	}
+	padded, _ := ApplyLengthPolicy(seqs, PadShorter, 0)
+	for _, s := range padded {
+		if len(s) != len(x3) {
+			t.Errorf("sequence not padded: %v", s)
+		}
+	}
+	optScore, err := SolveWithLengthPolicy([]string{x1, x2, x3, x4}, Strict, 0)
+	if err != nil || optScore != 45 {
+		t.Error("Incorrect score.")
+	}
+}
+
+func TestEditDistanceConfig(t *testing.T) {
+	pairs := [][2]string{
+		{"ACGT", "AGT"},
+		{"GATTACA", "GATACA"},
+		{"ACGTACGT", "ACGTTCGT"},
+		{"AATTATGG", "AATTATGG"},
+		{"TTTT", "GACA"},
+		{x1, x4},
+		{x2, "T"},
+	}
+	for _, p := range pairs {
+		optScore := SolveGlobal(p[:], bio.EditDistanceConfig())
+		if d := levenshtein(p[0], p[1]); optScore != -float64(d) {
+			t.Errorf("%v: got %v, edit distance %v", p, optScore, d)
+		}
+	}
+}
+
+func levenshtein(a, b string) int {
+	d := bio.Slice2D(len(a)+1, len(b)+1, 0)
+	for i := range d {
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			sub := 1
+			if a[i-1] == b[j-1] {
+				sub = 0
+			}
+			d[i][j] = -bio.Max(-(d[i-1][j] + 1), -(d[i][j-1] + 1), -(d[i-1][j-1] + sub))
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func TestSolveGlobal(t *testing.T) {
+	optScore := SolveGlobal([]string{x1, x2, x3, x4}, bio.DefaultScoreConfig())
+	t.Log(optScore)
+	if optScore != 39 {
+		t.Error("Incorrect score.")
+	}
+}
+
+func TestSolveSequences(t *testing.T) {
+	seqs := bio.AsToSeqs([]string{x1, x2, x3, x4})
+	if SolveSequences(seqs, bio.DefaultScoreConfig()) != 45 {
+		t.Error("Incorrect score.")
+	}
+	if SolveSequences(seqs, bio.EditDistanceConfig()) != SolveWithConfig([]string{x1, x2, x3, x4}, bio.EditDistanceConfig()) {
+		t.Error("parsed and string inputs disagree")
+	}
+}
+
+func TestSolveAlignment(t *testing.T) {
+	seqStrings := []string{x1, x2, x3, x4}
+	a := SolveAlignment(seqStrings, bio.DefaultScoreConfig())
+	if a.Score != 45 {
+		t.Error("Incorrect score.")
+	}
+	checkAlignment(t, a, seqStrings)
+	b := SolveAlignment(seqStrings, bio.DefaultScoreConfig())
+	for i := range a.Rows {
+		if !basesEqual(a.Rows[i].Bases, b.Rows[i].Bases) {
+			t.Error("traceback is not deterministic")
+		}
+	}
+
+	// in global mode every column is scored, so rescoring must agree
+	m := newMultiDP(bio.AsToSeqs(seqStrings), bio.DefaultScoreConfig())
+	m.global = true
+	g := m.alignment(nil)
+	checkAlignment(t, g, seqStrings)
+	if float64(bio.SPScore(g.Rows))/2 != g.Score {
+		t.Errorf("global alignment scores %v, dp reported %v", float64(bio.SPScore(g.Rows))/2, g.Score)
+	}
+}
+
+func TestSolveAlignmentRand(t *testing.T) {
+	seqStrings := []string{"GAAT", "GAT", "GAAT"}
+	seen := map[string]bool{}
+	for seed := int64(0); seed < 20; seed++ {
+		a := SolveAlignmentRand(seqStrings, bio.DefaultScoreConfig(), rand.New(rand.NewSource(seed)))
+		checkAlignment(t, a, seqStrings)
+		if a.Score != Solve(seqStrings) {
+			t.Error("Incorrect score.")
+		}
+		again := SolveAlignmentRand(seqStrings, bio.DefaultScoreConfig(), rand.New(rand.NewSource(seed)))
+		if fmt.Sprint(again.Rows[0].Bases) != fmt.Sprint(a.Rows[0].Bases) {
+			t.Error("same seed produced a different alignment")
+		}
+		key := ""
+		for _, row := range a.Rows {
+			key += fmt.Sprint(row.Bases)
+		}
+		seen[key] = true
+	}
+	t.Log(len(seen))
+	if len(seen) < 2 {
+		t.Error("expected different seeds to sample different co-optimal alignments")
+	}
+}
+
+// checkAlignment verifies that a is rectangular and that removing gaps from
+// each row gives back the input sequence.
+func checkAlignment(t *testing.T, a *msa.Alignment, seqStrings []string) {
+	t.Helper()
+	if len(a.Rows) != len(seqStrings) {
+		t.Fatalf("expected %v rows, got %v", len(seqStrings), len(a.Rows))
+	}
+	for i, row := range a.Rows {
+		if len(row.Bases) != a.Width() {
+			t.Errorf("row %v has length %v, expected %v", i, len(row.Bases), a.Width())
+		}
+		var ungapped []bio.Base
+		for _, b := range row.Bases {
+			if b != bio.X {
+				ungapped = append(ungapped, b)
+			}
+		}
+		if !basesEqual(ungapped, bio.AToSeq(seqStrings[i]).Bases) {
+			t.Errorf("row %v does not spell %v", i, seqStrings[i])
+		}
+	}
+}
+
+func basesEqual(a, b []bio.Base) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMaxSequences(t *testing.T) {
+	seqs := []string{x5, x6, x7, x8, x5, x6}
+	if _, err := SolveE(seqs); !errors.Is(err, ErrTooManySequences) {
+		t.Errorf("expected ErrTooManySequences, got %v", err)
+	}
+	defer func(n int) { MaxSequences = n }(MaxSequences)
+	MaxSequences = 6
+	optScore, err := SolveE(seqs)
+	if err != nil || optScore != 90 {
+		t.Errorf("got %v, %v", optScore, err)
+	}
+}
+
+func TestMaxWork(t *testing.T) {
+	long := strings.Repeat(x1, 25)
+	seqs := []string{long, long, long, long}
+	if _, err := SolveE(seqs); !errors.Is(err, ErrInfeasible) {
+		t.Errorf("expected ErrInfeasible, got %v", err)
+	}
+	if _, err := SolveAlignmentE(seqs, bio.DefaultScoreConfig()); !errors.Is(err, ErrInfeasible) {
+		t.Errorf("expected ErrInfeasible, got %v", err)
+	}
+	defer func(w int64) { MaxWork = w }(MaxWork)
+	MaxWork = 15 * 9 * 9 * 9 * 9
+	if _, err := SolveE([]string{x1, x1, x1, x1}); err != nil {
+		t.Error(err)
+	}
+	MaxWork--
+	if _, err := SolveE([]string{x1, x1, x1, x1}); !errors.Is(err, ErrInfeasible) {
+		t.Errorf("expected ErrInfeasible, got %v", err)
+	}
+}
+
+func TestSolveFASTAPair(t *testing.T) {
+	in := ">x1\n" + x1 + "\n>x2\n" + x2 + "\n>x3\n" + x3 + "\n>dup\nA\n>dup\nC\n"
+	a, err := SolveFASTAPair(strings.NewReader(in), "x3", "x1", bio.DefaultScoreConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAlignment(t, a, []string{x3, x1})
+	if a.IDs[0] != "x3" || a.Score != Solve([]string{x3, x1}) {
+		t.Error("Incorrect alignment.")
+	}
+	for _, id := range []string{"missing", "dup"} {
+		if _, err := SolveFASTAPair(strings.NewReader(in), "x1", id, bio.DefaultScoreConfig()); err == nil {
+			t.Errorf("expected error for id %v", id)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	m := newMultiDP(bio.AsToSeqs([]string{x1, x2, x3}), cfg)
+	m.solve()
+	// same shapes as x1, x2, x3 but different bases
+	others := []string{"CCCCCCCC", "TTTTTTTTT", "CCTTCCTTC"}
+	m.reset(bio.AsToSeqs(others))
+	if m.solve() != Solve(others) {
+		t.Error("stale cached scores leaked into the reset solver")
+	}
+	m.reset(bio.AsToSeqs([]string{x1, x2, x3}))
+	if m.solve() != Solve([]string{x1, x2, x3}) {
+		t.Error("Incorrect score after reset.")
+	}
+}
+
+func TestOptimalMoves(t *testing.T) {
+	// the final T pairs with T in every optimal alignment
+	moves := OptimalFinalMoves([]string{"GAAT", "GAT"}, bio.DefaultScoreConfig())
+	if len(moves) != 1 || !arraysMatch(moves[0], []int{1, 1}) {
+		t.Errorf("got %v", moves)
+	}
+	// GAA against GA ends in either an A/A or an A/- column
+	m := newMultiDP(bio.AsToSeqs([]string{"GAA", "GA"}), bio.DefaultScoreConfig())
+	m.solve()
+	if moves := m.OptimalMoves(m.maxIndices()); len(moves) != 2 {
+		t.Errorf("expected two co-optimal moves, got %v", moves)
+	}
+}
+
+func TestSolveFixedReference(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	// freely aligned, the reference AAT takes a gap against AGAT
+	seqStrings := []string{"AAT", "AGAT"}
+	if a := SolveAlignment(seqStrings, cfg); a.Width() != 4 {
+		t.Logf("free alignment width: %v", a.Width())
+		t.Error("Expected the free alignment to gap the reference.")
+	}
+	for _, tc := range []struct {
+		seqs       []string
+		ref        int
+		insertions string
+	}{
+		{seqStrings, 0, "[{1 1 [G]}]"},
+		{[]string{"TTACGT", "ACGT"}, 1, "[{0 0 [T T]}]"},
+		{[]string{"ACGTACGT", "ACGTGGACGT", "ACGTACGTCC"}, 0, "[{1 4 [G G]} {2 8 [C C]}]"},
+		{[]string{x1, x2, x3, x4}, 2, ""},
+	} {
+		a, insertions, err := SolveFixedReference(tc.seqs, tc.ref, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Width() != len(tc.seqs[tc.ref]) {
+			t.Error("Reference was gapped.")
+		}
+		if tc.insertions != "" && fmt.Sprint(insertions) != tc.insertions {
+			t.Errorf("got insertions %v, expected %v", insertions, tc.insertions)
+		}
+		if s, _ := msa.ScoreAlignment(rowsOf(a), cfg); a.Score != s || a.Exact {
+			t.Errorf("reported %v, rescored %v", a.Score, s)
+		}
+		// splicing the insertions back in must give every sequence in full
+		for i, row := range a.Rows {
+			var bases []bio.Base
+			for c := 0; c <= a.Width(); c++ {
+				for _, ins := range insertions {
+					if ins.Seq == i && ins.Pos == c {
+						bases = append(bases, ins.Bases...)
+					}
+				}
+				if c < a.Width() && row.Bases[c] != bio.X {
+					bases = append(bases, row.Bases[c])
+				}
+			}
+			if !basesEqual(bases, bio.AToSeq(tc.seqs[i]).Bases) {
+				t.Errorf("row %v with its insertions does not spell %v", i, tc.seqs[i])
+			}
+		}
+	}
+	if _, _, err := SolveFixedReference(seqStrings, 2, cfg); err == nil {
+		t.Error("Expected an error for an out of range reference.")
+	}
+}
+
+func TestForbid(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	// a mismatch (-4) beats two gaps (-6) until the substitution is forbidden
+	if score := SolveGlobal([]string{"A", "G"}, cfg); score != -2 {
+		t.Log(score)
+		t.Error("Incorrect score.")
+	}
+	cfg.Forbid(bio.A, bio.G)
+	if score := SolveGlobal([]string{"A", "G"}, cfg); score != -3 {
+		t.Log(score)
+		t.Error("Incorrect score.")
+	}
+	a := SolveAlignment([]string{"CAT", "CGT"}, cfg)
+	checkAlignment(t, a, []string{"CAT", "CGT"})
+	if a.Width() != 4 {
+		t.Log(a.Width())
+		t.Error("Expected the forbidden pair to be gapped.")
+	}
+}
+
+func TestSolveWithStats(t *testing.T) {
+	seqStrings := []string{x1, x2, x3}
+	score, stats := SolveWithStats(seqStrings, bio.DefaultScoreConfig())
+	t.Log(stats)
+	if score != Solve(seqStrings) {
+		t.Error("Incorrect score.")
+	}
+	total := int64((len(x1) + 1) * (len(x2) + 1) * (len(x3) + 1))
+	if stats.CellsTotal != total || stats.CellsComputed <= 0 || stats.CellsComputed > total {
+		t.Error("Incorrect cell counts.")
+	}
+	// two tables of one int64 per cell, whatever the GOARCH
+	if stats.PeakBytes != 2*8*total {
+		t.Errorf("got PeakBytes %v, expected %v", stats.PeakBytes, 2*8*total)
+	}
+}
+
+func TestSolveAnchored(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{"ACGTTGCA", "ACTTGGCA", "AGTTGCA"}
+	a, err := SolveAnchored(seqStrings, [][]int{{4, 4, 3}}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAlignment(t, a, seqStrings)
+	t.Log(a.Score)
+	// with no anchors the single block is the global alignment
+	whole, _ := SolveAnchored(seqStrings, nil, cfg)
+	if whole.Score != SolveGlobal(seqStrings, cfg) || a.Score > whole.Score {
+		t.Error("Incorrect score.")
+	}
+	// the anchored score is the score of the stitched alignment
+	if a.Score != bio.ToNatural(bio.SPScore(a.Rows)) {
+		t.Error("Incorrect score.")
+	}
+	for _, anchors := range [][][]int{
+		{{4, 4}},
+		{{4, 4, 3}, {2, 5, 5}},
+		{{4, 4, 9}},
+	} {
+		if _, err := SolveAnchored(seqStrings, anchors, cfg); err == nil {
+			t.Errorf("expected error for anchors %v", anchors)
+		}
+	}
+}
+
+func TestSolveDebug(t *testing.T) {
+	score, scores, moves := SolveDebug([]string{"GAT", "GT"}, bio.DefaultScoreConfig())
+	if score != 4.5 || scores.At([]int{3, 2}) != 9 {
+		t.Log(score, scores.At([]int{3, 2}))
+		t.Error("Incorrect score.")
+	}
+	// G/G, A/-, T/T walked back from the final cell
+	for _, step := range []struct {
+		idxs []int
+		move int
+	}{
+		{[]int{3, 2}, 3},
+		{[]int{2, 1}, 1},
+		{[]int{1, 1}, 3},
+		{[]int{0, 1}, 0},
+	} {
+		if m := moves.At(step.idxs); m != step.move {
+			t.Errorf("cell %v: got move %v, expected %v", step.idxs, m, step.move)
+		}
+	}
+}
+
+func TestAlignable(t *testing.T) {
+	related := []string{"ACGTTGCA", "ACGTAGCA", "ACGTTGC"}
+	if !Alignable(related, 70) {
+		t.Error("Expected related sequences to be alignable.")
+	}
+	if Alignable(append(related, "TTTTTTTT"), 70) {
+		t.Error("Expected an unrelated sequence to fail the check.")
+	}
+	if !Alignable([]string{"ACGT"}, 100) {
+		t.Error("Expected a single sequence to be alignable.")
+	}
+}
+
+func TestSolveWithin(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, x2, x3, x4}
+	if score, upper := SolveWithin(seqStrings, cfg, 0); score != 45 || upper != 45 {
+		t.Log(score, upper)
+		t.Error("Incorrect score.")
+	}
+	for _, eps := range []int{2, 10, 40} {
+		score, upper := SolveWithin(seqStrings, cfg, eps)
+		t.Log(eps, score, upper)
+		if score > 45 || upper < 45 || 45-score > bio.ToNatural(int64(eps)) {
+			t.Error("Incorrect bound.")
+		}
+	}
+	// the bound can prune moves outright, even with no slack
+	m := newMultiDP(bio.AsToSeqs(seqStrings), cfg)
+	m.bound = m.pairwiseBound()
+	if m.solve() != 45 {
+		t.Error("Incorrect score.")
+	}
+	_, stats := SolveWithStats(seqStrings, cfg)
+	if m.cells >= stats.CellsComputed {
+		t.Logf("%v cells with pruning, %v without", m.cells, stats.CellsComputed)
+		t.Error("Expected pruning to skip cells.")
+	}
+}
+
+func TestSolveStranded(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	// the second read is the reverse complement of AACGTTTG
+	seqStrings := []string{"AACGTTTG", "CAAACGTT", "AACGTTG"}
+	a, reversed, err := SolveStranded(seqStrings, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(reversed, a.Score)
+	if fmt.Sprint(reversed) != "[false true false]" {
+		t.Error("Incorrect strands.")
+	}
+	checkAlignment(t, a, []string{"AACGTTTG", "AACGTTTG", "AACGTTG"})
+	if _, _, err := SolveStranded(nil, cfg); err == nil {
+		t.Error("expected error for no sequences")
+	}
+}
+
+func TestSolveFASTAGroups(t *testing.T) {
+	in := ">geneA|1\n" + x1 + "\n>geneB|1\nGAT\n>geneA|2\n" + x2 + "\n>geneB|2\nGT\n"
+	for i := 0; i < MaxSequences+1; i++ {
+		in += fmt.Sprintf(">big|%v\nAC\n", i)
+	}
+	family := func(id string) string { return strings.Split(id, "|")[0] }
+	groups, err := SolveFASTAGroups(strings.NewReader(in), family, bio.DefaultScoreConfig())
+	t.Log(err)
+	if !errors.Is(err, ErrTooManySequences) || !strings.Contains(err.Error(), `"big"`) {
+		t.Error("expected an error for the oversized group")
+	}
+	if len(groups) != 2 || groups["big"] != nil {
+		t.Fatalf("got %v groups", len(groups))
+	}
+	checkAlignment(t, groups["geneA"], []string{x1, x2})
+	if groups["geneB"].IDs[1] != "geneB|2" || groups["geneB"].Score != 4.5 {
+		t.Error("Incorrect alignment.")
+	}
+}
+
+func TestSolveWindow(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{"TTTTGATCCCC", "AAGTAA"}
+	a, positions, err := SolveWindow(seqStrings, [][2]int{{4, 7}, {2, 4}}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAlignment(t, a, []string{"GAT", "GT"})
+	t.Log(positions)
+	if fmt.Sprint(positions) != "[[4 5 6] [2 -1 3]]" {
+		t.Error("Incorrect positions.")
+	}
+	for _, windows := range [][][2]int{
+		{{4, 7}},
+		{{4, 4}, {2, 4}},
+		{{4, 7}, {2, 7}},
+	} {
+		if _, _, err := SolveWindow(seqStrings, windows, cfg); err == nil {
+			t.Errorf("expected error for windows %v", windows)
+		}
+	}
+}
+
+func TestExact(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, x2, x3}
+	a := SolveAlignment(seqStrings, cfg)
+	if !a.Exact {
+		t.Error("expected the exact solver to mark its result")
+	}
+	a.MoveGap(0, 0, 0)
+	a.Rescore(cfg)
+	if a.Exact {
+		t.Error("expected rescoring to clear Exact")
+	}
+	if a, _, _ := SolveFixedReference(seqStrings, 1, cfg); a.Exact {
+		t.Error("expected a fixed reference result not to be exact")
+	}
+	if a, _ := SolveAnchored(seqStrings, nil, cfg); !a.Exact {
+		t.Error("expected an unanchored result to be exact")
+	}
+	if a, _, _ := SolveStranded(seqStrings, cfg); a.Exact {
+		t.Error("expected a stranded result not to be exact")
+	}
+	if a, _ := msa.ReweightAlign(seqStrings, 1, cfg); a.Exact {
+		t.Error("expected a progressive result not to be exact")
+	}
+}
+
+func TestSolveIterative(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, x2, x3}
+	expected := SolveWithConfig(seqStrings, cfg)
+	for _, order := range []FillOrder{RowMajor, AntiDiagonal, Morton} {
+		if score := SolveIterative(seqStrings, cfg, order); score != expected {
+			t.Errorf("order %v: got %v, expected %v", order, score, expected)
+		}
+	}
+	if !mortonLess([]int{1, 0}, []int{0, 2}) || mortonLess([]int{0, 2}, []int{1, 1}) {
+		t.Error("Incorrect Z-order.")
+	}
+}
+
+func TestSolveIterativeMatchesRecursive(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for trial := 0; trial < 30; trial++ {
+		var seqStrings []string
+		for i := 0; i < 2+trial%3; i++ {
+			seqStrings = append(seqStrings, randomSeq(rng, rng.Intn(9)))
+		}
+		for _, cfg := range []bio.ScoreConfig{bio.DefaultScoreConfig(), bio.EditDistanceConfig()} {
+			if s, r := SolveIterative(seqStrings, cfg, RowMajor), SolveWithConfig(seqStrings, cfg); s != r {
+				t.Errorf("%v: iterative %v, recursive %v", seqStrings, s, r)
+			}
+		}
+	}
+}
+
+func TestIdenticalFastPath(t *testing.T) {
+	seqs := bio.AsToSeqs([]string{x1, x1, x1, x1})
+	for _, cfg := range []bio.ScoreConfig{bio.DefaultScoreConfig(), bio.EditDistanceConfig()} {
+		score, ok := identicalScore(seqs, cfg)
+		if !ok {
+			t.Fatal("expected the fast path")
+		}
+		mdp := newMultiDP(seqs, cfg)
+		if d := mdp.solve(); bio.ToNatural(score) != d {
+			t.Errorf("fast path %v, DP %v", bio.ToNatural(score), d)
+		}
+		mdp = newMultiDP(seqs, cfg)
+		mdp.global = true
+		if d := mdp.solve(); bio.ToNatural(score) != d {
+			t.Errorf("global: fast path %v, DP %v", bio.ToNatural(score), d)
+		}
+	}
+	cfg := bio.DefaultScoreConfig()
+	if _, ok := identicalScore(bio.AsToSeqs([]string{x1, x1, x2}), cfg); ok {
+		t.Error("expected no fast path for differing sequences")
+	}
+	if _, ok := identicalScore(bio.AsToSeqs([]string{"ACN", "ACN"}), cfg); ok {
+		t.Error("expected no fast path with unknown bases")
+	}
+	cfg.Gap = 4
+	if _, ok := identicalScore(seqs, cfg); ok {
+		t.Error("expected no fast path when gaps pay")
+	}
+}
+
+func TestSolveAlignmentPairs(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, x2, x3}
+	a, err := SolveAlignmentPairs(seqStrings, cfg, [][2]int{{1, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(a.Score)
+	// the third sequence is free context, so only the first pair counts
+	if expected := SolveWithConfig(seqStrings[:2], cfg); a.Score != expected {
+		t.Errorf("got %v, expected %v", a.Score, expected)
+	}
+	checkAlignment(t, a, seqStrings)
+	all, _ := SolveAlignmentPairs(seqStrings, cfg, [][2]int{{0, 1}, {0, 2}, {1, 2}})
+	if all.Score != SolveWithConfig(seqStrings, cfg) {
+		t.Error("Incorrect score.")
+	}
+	for _, pairs := range [][][2]int{{{0, 3}}, {{1, 1}}, {{0, 1}, {1, 0}}} {
+		if _, err := SolveAlignmentPairs(seqStrings, cfg, pairs); err == nil {
+			t.Errorf("expected error for pairs %v", pairs)
+		}
+	}
+	cfg.GapModel = bio.Linear
+	if _, err := SolveAlignmentPairs(seqStrings, cfg, nil); err == nil {
+		t.Error("expected error for the linear gap model")
+	}
+}
+
+func TestScoreBounds(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	for _, seqStrings := range [][]string{{x1, x2, x3}, {x1, x2, x3, x4}, {x5, x6, x7, x8}, {x3, "TTTT"}} {
+		lower, upper := ScoreBounds(seqStrings, cfg)
+		score := SolveWithConfig(seqStrings, cfg)
+		t.Log(lower, score, upper)
+		if lower > score || score > upper {
+			t.Errorf("%v: %v not within [%v, %v]", seqStrings, score, lower, upper)
+		}
+	}
+	cfg.GapModel = bio.Linear
+	if _, upper := ScoreBounds([]string{x1, x2}, cfg); !math.IsInf(upper, 1) {
+		t.Errorf("linear: got upper %v", upper)
+	}
+}
+
+func TestEmptySequences(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, "", x2, "--"}
+	if _, err := SolveE(seqStrings); !errors.Is(err, ErrEmptySequence) {
+		t.Errorf("expected ErrEmptySequence, got %v", err)
+	}
+	if _, err := SolveE([]string{x1, "  \n", x2}); !errors.Is(err, ErrEmptySequence) {
+		t.Errorf("whitespace: expected ErrEmptySequence, got %v", err)
+	}
+	in := ">a\n" + x1 + "\n>b\n\n"
+	if _, err := SolveFASTAPair(strings.NewReader(in), "a", "b", cfg); !errors.Is(err, ErrEmptySequence) {
+		t.Errorf("fasta: expected ErrEmptySequence, got %v", err)
+	}
+	defer func(p EmptyPolicy) { EmptySequences = p }(EmptySequences)
+	EmptySequences = SkipEmpty
+	if score, err := SolveE(seqStrings); err != nil || score != Solve([]string{x1, x2}) {
+		t.Errorf("got %v, %v", score, err)
+	}
+	a, err := SolveAlignmentE(seqStrings, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAlignment(t, a, []string{x1, "", x2, ""})
+	if rows := len(a.Rows); rows != 4 || a.Score != Solve([]string{x1, x2}) {
+		t.Errorf("got %v rows scoring %v", rows, a.Score)
+	}
+	if a, err := SolveAlignmentE([]string{"", "-"}, cfg); err != nil || len(a.Rows) != 2 {
+		t.Errorf("all empty: got %v, %v", a, err)
+	}
+	// gap characters are dropped, so a gapped row solves like its residues
+	if score, _ := SolveE([]string{"AA-TTA-TGG", x2}); score != Solve([]string{x1, x2}) {
+		t.Error("Incorrect score.")
+	}
+}
+
+func TestSolveCodons(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	// the second sequence lacks the middle codon
+	seqStrings := []string{"ATGAAACCCGGG", "ATGCCCGGG", "ATGAAACCTGGG"}
+	a, err := SolveCodons(seqStrings, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAlignment(t, a, seqStrings)
+	for i, row := range a.Rows {
+		run := 0
+		for _, b := range append(row.Bases, bio.A) {
+			if b == bio.X {
+				run++
+				continue
+			}
+			if run%3 != 0 {
+				t.Errorf("row %v has a gap run of %v", i, run)
+			}
+			run = 0
+		}
+	}
+	if s, _ := msa.ScoreAlignment(rowsOf(a), cfg); s != a.Score {
+		t.Errorf("reported %v, rescored %v", a.Score, s)
+	}
+	if _, err := SolveCodons([]string{"ATGA", "ATG"}, cfg); err == nil {
+		t.Error("expected error for a partial codon")
+	}
+}
+
+func rowsOf(a *msa.Alignment) (rows []string) {
+	for _, row := range a.Rows {
+		s := ""
+		for _, b := range row.Bases {
+			s += string("ACGT-N"[b])
+		}
+		rows = append(rows, s)
+	}
+	return
+}
+
+func TestSolveAlignmentCompact(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	for _, seqStrings := range [][]string{{x1, x2, x3}, {x1, x2, x3, x4}, {"CGGC", "AAGAA", "AGTTC"}} {
+		a := SolveAlignment(seqStrings, cfg)
+		c := SolveAlignmentCompact(seqStrings, cfg)
+		t.Log(a.Width(), c.Width())
+		checkAlignment(t, c, seqStrings)
+		if c.Score != a.Score || c.Width() > a.Width() {
+			t.Errorf("%v: compact %v wide scoring %v, default %v wide scoring %v",
+				seqStrings, c.Width(), c.Score, a.Width(), a.Score)
+		}
+	}
+	// the first tie taken by SolveAlignment leads to a wider alignment here
+	if w := SolveAlignmentCompact([]string{"CGGC", "AAGAA", "AGTTC"}, cfg).Width(); w != 6 {
+		t.Errorf("got width %v", w)
+	}
+}
+
+func TestSolveConstrained(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, x2, x3}
+	// a deliberately poor guide for the first two sequences, with an
+	// all-gap column that is not part of the constraint
+	guide, _ := msa.NewAlignment(nil, []string{x1 + "----------", "---------" + x2})
+	a, err := SolveConstrained(seqStrings, guide, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAlignment(t, a, seqStrings)
+	var projected [2]string
+	for c := 0; c < a.Width(); c++ {
+		col := a.Column(c)
+		if col[0] == bio.X && col[1] == bio.X {
+			continue
+		}
+		for g := range projected {
+			projected[g] += string("ACGT-N"[col[g]])
+		}
+	}
+	if projected[0] != x1+"---------" || projected[1] != "--------"+x2 {
+		t.Errorf("guide not kept: %v", projected)
+	}
+	if free := SolveGlobal(seqStrings, cfg); a.Score >= free {
+		t.Errorf("constrained %v, unconstrained %v", a.Score, free)
+	}
+	bad, _ := msa.NewAlignment(nil, []string{x1, x1})
+	if _, err := SolveConstrained(seqStrings, bad, cfg); err == nil {
+		t.Error("expected error for a guide row used twice")
+	}
+}
+
+func TestAllPairwise(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, x2, x3, x4}
+	results := AllPairwise(seqStrings, cfg)
+	if len(results) != 6 {
+		t.Fatalf("got %v results", len(results))
+	}
+	n := len(seqStrings)
+	for k, r := range results {
+		if k != r.I*(2*n-r.I-1)/2+r.J-r.I-1 {
+			t.Errorf("pair %v %v at %v", r.I, r.J, k)
+		}
+		pair := []string{seqStrings[r.I], seqStrings[r.J]}
+		if r.Score != SolveGlobal(pair, cfg) || r.Alignment.Score != r.Score {
+			t.Errorf("pair %v %v: got %v", r.I, r.J, r.Score)
+		}
+		if s, _ := msa.ScoreAlignment(rowsOf(r.Alignment), cfg); s != r.Score {
+			t.Errorf("pair %v %v: reported %v, rescored %v", r.I, r.J, r.Score, s)
+		}
+		checkAlignment(t, r.Alignment, pair)
+	}
+}
+
+func TestSolveConsistency(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{x1, x2, x3, x4}
+	a, objective := SolveConsistency(seqStrings, cfg)
+	t.Log(objective, a.Score)
+	checkAlignment(t, a, seqStrings)
+	if s, _ := msa.ScoreAlignment(rowsOf(a), cfg); s != a.Score {
+		t.Errorf("reported %v, rescored %v", a.Score, s)
+	}
+	if objective <= 0 {
+		t.Error("Incorrect objective.")
+	}
+	// two identical sequences: every residue pair has full identity and
+	// no third sequence to extend through
+	if b, objective := SolveConsistency([]string{"ACGT", "ACGT"}, cfg); objective != 400 || b.Width() != 4 {
+		t.Errorf("identical: got %v, width %v", objective, b.Width())
+	}
+	// the prefixes share nothing and a mismatch costs more than two gaps, so
+	// only the residues of the common suffix may be paired in the library
+	cfg.Mismatch = -20
+	pair := []string{"CCCCACGT", "GGACGT"}
+	lib := primaryLibrary(pair, cfg)
+	for p, q := range lib.match[0][1] {
+		if q >= 0 && (p < 4 || pair[0][p] != pair[1][q]) {
+			t.Errorf("library pairs %v with %v", p, q)
+		}
+	}
+}
+
+func TestSolveAffine(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	// with open equal to extend the model is the linear one
+	for _, seqStrings := range [][]string{{x1, x2}, {x1, x2, x3}, {x5, "ACGT"}} {
+		if s, g := SolveAffine(seqStrings, cfg, cfg.Gap, cfg.Gap), SolveGlobal(seqStrings, cfg); s != g {
+			t.Errorf("%v: affine %v, global %v", seqStrings, s, g)
+		}
+	}
+	// one run of three gaps: 8 matches, one open and two extends
+	if s := SolveAffine([]string{"ACGTTTTACGT", "ACGTACGT"}, cfg, -10, -1); s != bio.ToNatural(8*6-10-2) {
+		t.Errorf("got %v", s)
+	}
+	// the run costs far less than the same gaps scattered, which pay an open
+	// each, and the solver agrees with the run's score
+	affine := bio.AffineGapCost(-10, -1)
+	run, _ := msa.NewAlignment(nil, []string{"ACGTTTTACGT", "ACGT---ACGT"})
+	scattered, _ := msa.NewAlignment(nil, []string{"ACGTTTTACGT", "ACGT-A-C-GT"})
+	if r := run.RunScore(cfg, affine); r != SolveAffine([]string{"ACGTTTTACGT", "ACGTACGT"}, cfg, -10, -1) ||
+		scattered.RunScore(cfg, affine) >= r {
+		t.Error("Incorrect score.")
+	}
+	if s := SolveAffine([]string{"", ""}, cfg, -10, -1); s != 0 {
+		t.Errorf("empty: got %v", s)
+	}
+}
+
+func TestSolveProtein(t *testing.T) {
+	// the codes outside ACGT would all be N, and score 0, if read as DNA
+	if s := SolveProtein([]string{"MKWVLE", "MKWVLE"}); s != bio.ToNatural(6*6) {
+		t.Errorf("got %v", s)
+	}
+	if s, d := SolveProtein([]string{"MKWVLE", "MRWVLE", "MKWLE"}), SolveWithConfig([]string{"MKWVLE", "MRWVLE", "MKWLE"}, bio.DefaultScoreConfig()); s <= d {
+		t.Errorf("protein %v, as DNA %v", s, d)
+	}
+}
+
+func TestSolveWithMatrix(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	identity := func(a, b bio.Base) int {
+		if a == b {
+			return cfg.Match
+		}
+		return cfg.Mismatch
+	}
+	seqStrings := []string{"MKWVLE", "MRWVLE", "MKWLE"}
+	if s, p := SolveWithMatrix(seqStrings, identity, cfg.Gap), SolveProtein(seqStrings); s != p {
+		t.Errorf("matrix %v, protein %v", s, p)
+	}
+	// rewarding K against R makes the mismatch column score like a match
+	k, r := bio.Residue('K'), bio.Residue('R')
+	similar := func(a, b bio.Base) int {
+		if (a == k && b == r) || (a == r && b == k) {
+			return cfg.Match
+		}
+		return identity(a, b)
+	}
+	if s := SolveWithMatrix([]string{"MKW", "MRW"}, similar, cfg.Gap); s != bio.ToNatural(int64(3*cfg.Match)) {
+		t.Errorf("got %v", s)
+	}
+	// property scores plug in as a matrix: I/V and K/R differ in hydropathy
+	// by 0.3 and 0.6, which at scale 10 cost 6 and 12 doubled
+	property := bio.ScoreConfig{Gap: -8, Alphabet: bio.Protein}
+	property.SetMatrix(bio.KyteDoolittle().Matrix(10))
+	if s := SolveGlobal([]string{"ILK", "VLR"}, property); s != -9 {
+		t.Errorf("property: got %v", s)
+	}
+	// the blosum62 preset reads proteins and scores them by the matrix
+	blosum, err := bio.LookupPreset("blosum62")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair := []string{"HEAGAWGHEE", "PAWHEAE"}
+	want := SolveWithMatrix(pair, bio.BLOSUM62(), blosum.Gap)
+	if s := SolveWithConfig(pair, blosum); s != want || s <= 0 {
+		t.Errorf("preset %v, matrix %v", s, want)
+	}
+	if a := SolveAlignment(pair, blosum); a.Score != want {
+		t.Errorf("alignment %v, matrix %v", a.Score, want)
+	}
+}
+
+func TestSolveChecked(t *testing.T) {
+	if s, err := SolveChecked([]string{x1, "acg-tn"}); err != nil || s != Solve([]string{x1, "ACGTN"}) {
+		t.Errorf("got %v, %v", s, err)
+	}
+	if _, err := SolveChecked(nil); !errors.Is(err, ErrNoSequences) {
+		t.Errorf("got %v", err)
+	}
+	if _, err := SolveChecked([]string{x1, ""}); !errors.Is(err, ErrEmptySequence) {
+		t.Errorf("got %v", err)
+	}
+	_, err := SolveChecked([]string{x1, "AC GT"})
+	if !errors.Is(err, ErrInvalidCharacter) || !strings.Contains(err.Error(), "sequence 1 at position 2") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestSolveMixedCase(t *testing.T) {
+	a := SolveAlignment([]string{"aCgT", "AC\ngt"}, bio.DefaultScoreConfig())
+	b := SolveAlignment([]string{"ACGT", "ACGT"}, bio.DefaultScoreConfig())
+	if a.Score != b.Score || !slices.Equal(rowsOf(a), rowsOf(b)) {
+		t.Errorf("got %v %v, want %v %v", a.Score, rowsOf(a), b.Score, rowsOf(b))
+	}
+}
+
+func TestSolveFASTA(t *testing.T) {
+	in := ">a\r\nAATT\r\nATGG\r\n\r\n>b some description\nATAT\n\nATGG\n"
+	if s, err := SolveFASTA(strings.NewReader(in)); err != nil || s != Solve([]string{x1, "ATATATGG"}) {
+		t.Errorf("got %v, %v", s, err)
+	}
+	if _, err := SolveFASTA(strings.NewReader("\n\n")); !errors.Is(err, ErrNoSequences) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestSolveIUPAC(t *testing.T) {
+	if s := SolveIUPAC([]string{"ARYN", "AGCT"}); s != Solve([]string{"AGCT", "AGCT"}) {
+		t.Errorf("got %v", s)
+	}
+	if s := SolveIUPAC([]string{"ARYN", "AGCT"}); s <= Solve([]string{"ARYN", "AGCT"}) {
+		t.Errorf("got %v", s)
+	}
+}
+
+func TestSolveContext(t *testing.T) {
+	seqStrings := []string{x1, x2, x3}
+	if s, err := SolveContext(context.Background(), seqStrings); err != nil || s != Solve(seqStrings) {
+		t.Errorf("got %v, %v", s, err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	long := []string{randomSeq(rng, 120), randomSeq(rng, 120), randomSeq(rng, 120)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SolveContext(ctx, long); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := SolveContext(ctx, long); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func randomSeq(rng *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = "ACGT"[rng.Intn(4)]
+	}
+	return string(b)
+}
+
+func TestSolveParallel(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for trial := 0; trial < 20; trial++ {
+		var seqStrings []string
+		for i := 0; i < 2+trial%3; i++ {
+			seqStrings = append(seqStrings, randomSeq(rng, rng.Intn(12)))
+		}
+		for _, workers := range []int{0, 1, 3} {
+			if s, r := SolveParallel(seqStrings, workers), Solve(seqStrings); s != r {
+				t.Errorf("%v, %v workers: parallel %v, serial %v", seqStrings, workers, s, r)
+			}
+		}
+	}
+}
+
+func TestSolveWeighted(t *testing.T) {
+	seqStrings := []string{x1, x2, x3}
+	if s, err := SolveWeighted(seqStrings, []float64{1, 1, 1}); err != nil || s != Solve(seqStrings) {
+		t.Errorf("got %v, %v", s, err)
+	}
+	// a zero weight removes a sequence from the objective
+	if s, _ := SolveWeighted(seqStrings, []float64{1, 1, 0}); s != Solve([]string{x1, x2}) {
+		t.Errorf("got %v", s)
+	}
+	// uniform weights scale every pair by w²
+	if s, _ := SolveWeighted(seqStrings, []float64{0.5, 0.5, 0.5}); s != Solve(seqStrings)/4 {
+		t.Errorf("got %v", s)
+	}
+	if _, err := SolveWeighted(seqStrings, []float64{1, 1}); err == nil {
+		t.Error("expected a weight count mismatch to be rejected")
+	}
+	if _, err := SolveWeighted(seqStrings, []float64{1, -1, 1}); err == nil {
+		t.Error("expected a negative weight to be rejected")
+	}
+}
+
+func TestSolveLocal(t *testing.T) {
+	// the Smith-Waterman example with match 3, mismatch -3 and gap -2: GTT-AC
+	// over GTTGAC scores 13
+	cfg := bio.ScoreConfig{Match: 6, Mismatch: -6, Gap: -4}
+	s, ranges := SolveLocalWithConfig([]string{"TGTTACGG", "GGTTGACTA"}, cfg)
+	if s != 13 || !slices.Equal(ranges, [][2]int{{1, 6}, {1, 7}}) {
+		t.Errorf("got %v %v", s, ranges)
+	}
+	// a shared motif inside divergent flanks
+	s, ranges = SolveLocal([]string{"TTTTACGTACGTTTTT", "GGGACGTACGGGG"})
+	if s != bio.ToNatural(7*6) || !slices.Equal(ranges, [][2]int{{4, 11}, {3, 10}}) {
+		t.Errorf("got %v %v", s, ranges)
+	}
+	s, ranges = SolveLocal([]string{"AAAA", "CCCC"})
+	if s != 0 || !slices.Equal(ranges, [][2]int{{0, 0}, {0, 0}}) {
+		t.Errorf("got %v %v", s, ranges)
+	}
+}
+
+func TestTwoScore(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	cfgs := []bio.ScoreConfig{bio.DefaultScoreConfig(), bio.EditDistanceConfig(), {Match: 2, Mismatch: -1, Gap: -2, GapModel: bio.Linear}}
+	for trial := 0; trial < 50; trial++ {
+		seqs := bio.AsToSeqs([]string{randomSeq(rng, rng.Intn(15)), randomSeq(rng, rng.Intn(15))})
+		for _, cfg := range cfgs {
+			for _, global := range []bool{false, true} {
+				m := newMultiDP(seqs, cfg)
+				m.global = global
+				if s, g := m.twoScore(), m.optimalScore(m.maxIndices()); s != g {
+					t.Errorf("%v global %v: two-row %v, generic %v", seqs, global, s, g)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkSolvePair(b *testing.B) {
+	seqStrings := []string{strings.Repeat(x1, 25), strings.Repeat(x2, 25)}
+	for i := 0; i < b.N; i++ {
+		Solve(seqStrings)
+	}
+}
+
+func BenchmarkSolvePairGeneric(b *testing.B) {
+	seqs := bio.AsToSeqs([]string{strings.Repeat(x1, 25), strings.Repeat(x2, 25)})
+	for i := 0; i < b.N; i++ {
+		newMultiDP(seqs, bio.DefaultScoreConfig()).solve()
+	}
+}
+
+func TestSolveBuiltSequences(t *testing.T) {
+	s := bio.NewSequence()
+	for _, b := range []bio.Base{bio.A, bio.C, bio.G, bio.T} {
+		s.AppendBase(b)
+	}
+	if got := SolveSequences([]*bio.Sequence{s, s}, bio.DefaultScoreConfig()); got != Solve([]string{"ACGT", "ACGT"}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestSparseTable(t *testing.T) {
+	// four sequences of 300 would need a dense table of 301^4, about 8e9
+	// cells; guided by an alignment of all of them only the path is reached
+	rng := rand.New(rand.NewSource(9))
+	var seqStrings []string
+	for i := 0; i < 4; i++ {
+		seqStrings = append(seqStrings, randomSeq(rng, 300))
+	}
+	guide, _ := msa.NewAlignment(nil, seqStrings)
+	cfg := bio.DefaultScoreConfig()
+	a, err := SolveConstrained(seqStrings, guide, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := msa.ScoreAlignment(seqStrings, cfg); a.Score != want {
+		t.Errorf("got %v, want %v", a.Score, want)
+	}
+	// below DenseLimit the same solve is stored densely, with the same result
+	defer func(limit int) { DenseLimit = limit }(DenseLimit)
+	for _, limit := range []int{DenseLimit, 0} {
+		DenseLimit = limit
+		if s := Solve([]string{x1, x2, x3}); s != SolveIterative([]string{x1, x2, x3}, cfg, RowMajor) {
+			t.Errorf("limit %v: got %v", limit, s)
+		}
+	}
+}
+
+func BenchmarkDenseTable(b *testing.B) {
+	seqStrings := []string{x1 + x2, x2 + x3, x3 + x1}
+	for i := 0; i < b.N; i++ {
+		Solve(seqStrings)
+	}
+}
+
+func BenchmarkSparseTable(b *testing.B) {
+	defer func(limit int) { DenseLimit = limit }(DenseLimit)
+	DenseLimit = 0
+	seqStrings := []string{x1 + x2, x2 + x3, x3 + x1}
+	for i := 0; i < b.N; i++ {
+		Solve(seqStrings)
+	}
+}
+
+func TestSolveBanded(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	for trial := 0; trial < 20; trial++ {
+		seqStrings := []string{randomSeq(rng, 3+rng.Intn(8)), randomSeq(rng, 3+rng.Intn(8)), randomSeq(rng, 3+rng.Intn(8))}
+		full := Solve(seqStrings)
+		if s := SolveBanded(seqStrings, 10); s != full {
+			t.Errorf("%v: wide band %v, full %v", seqStrings, s, full)
+		}
+		for w := 0; w < 3; w++ {
+			if s := SolveBanded(seqStrings, w); s > full {
+				t.Errorf("%v: band %v scored %v above full %v", seqStrings, w, s, full)
+			}
+		}
+	}
+	// similar sequences only need a narrow band
+	if s := SolveBanded([]string{x1, "AATTTATGG"}, 1); s != Solve([]string{x1, "AATTTATGG"}) {
+		t.Errorf("got %v", s)
+	}
+}
+
+func TestSolveResult(t *testing.T) {
+	r, err := SolveResult([]string{x1, x2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back msa.AlignmentResult
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Score != r.Score || !slices.Equal(back.Rows, r.Rows) || !slices.Equal(back.Gaps, r.Gaps) || !back.Exact {
+		t.Errorf("got %+v, want %+v", back, r)
+	}
+	if !strings.Contains(string(data), "-") || r.Score != Solve([]string{x1, x2}) {
+		t.Errorf("got %s", data)
+	}
+	r.Score = math.Inf(-1)
+	if data, err := json.Marshal(r); err != nil || !strings.Contains(string(data), `"score":null`) {
+		t.Errorf("got %s, %v", data, err)
+	}
+	if _, err := SolveResult(nil); err == nil {
+		t.Error("expected no sequences to be rejected")
+	}
+}
+
+func TestSolveProgressive(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	base := randomSeq(rng, 40)
+	var seqStrings []string
+	for i := 0; i < 20; i++ {
+		// mutate and delete a few positions of a shared ancestor
+		b := []byte(base)
+		for k := 0; k < 4; k++ {
+			b[rng.Intn(len(b))] = "ACGT"[rng.Intn(4)]
+		}
+		cut := rng.Intn(len(b))
+		seqStrings = append(seqStrings, string(append(b[:cut:cut], b[cut+1:]...)))
+	}
+	rows, score, err := SolveProgressive(seqStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(seqStrings) {
+		t.Fatalf("got %v rows", len(rows))
+	}
+	for i, row := range rows {
+		if len(row) != len(rows[0]) || strings.ReplaceAll(row, "-", "") != seqStrings[i] {
+			t.Errorf("row %v: %v for %v", i, row, seqStrings[i])
+		}
+	}
+	if want, _ := msa.ScoreAlignment(rows, bio.DefaultScoreConfig()); score != want {
+		t.Errorf("got %v, want %v", score, want)
+	}
+	// on few sequences the heuristic cannot beat the exact optimum
+	if _, s, _ := SolveProgressive([]string{x1, x2, x3}); s > SolveGlobal([]string{x1, x2, x3}, bio.DefaultScoreConfig()) {
+		t.Errorf("got %v", s)
+	}
+	if rows, _, err := SolveProgressive(nil); rows != nil || !errors.Is(err, ErrNoSequences) {
+		t.Errorf("got %v, %v", rows, err)
+	}
+}
+
+func TestTerminalGapFree(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	cfg.TerminalGapFree = true
+	seqStrings := []string{"ACGTACGT", "CGTA"}
+	// the overhangs of the longer sequence are free, leaving 4 matches
+	if s := SolveGlobal(seqStrings, cfg); s != bio.ToNatural(int64(4*cfg.Match)) {
+		t.Errorf("got %v", s)
+	}
+	if s := SolveGlobal(seqStrings, bio.DefaultScoreConfig()); s >= bio.ToNatural(int64(4*cfg.Match)) {
+		t.Errorf("got %v", s)
+	}
+	// internal gaps are still charged
+	if s := SolveGlobal([]string{"ACGTTTACGT", "ACGTACGT"}, cfg); s != bio.ToNatural(int64(8*cfg.Match+2*cfg.Gap)) {
+		t.Errorf("got %v", s)
+	}
+	if s, _ := msa.ScoreAlignment([]string{"ACGTACGT", "-CGTA---"}, cfg); s != bio.ToNatural(int64(4*cfg.Match)) {
+		t.Errorf("got %v", s)
+	}
+	a, _ := msa.NewAlignment(nil, []string{"ACGTACGT", "-CG-A---"})
+	a.Rescore(cfg)
+	if a.Score != bio.ToNatural(int64(3*cfg.Match+cfg.Gap)) {
+		t.Errorf("got %v", a.Score)
+	}
+	three := []string{"TTACGTACGT", "ACGTAC", "CGTACGTGG"}
+	if s := SolveGlobal(three, cfg); s < SolveGlobal(three, bio.DefaultScoreConfig()) {
+		t.Errorf("got %v", s)
+	}
+}
+
+func TestMaskOrder(t *testing.T) {
+	masks := generateSubsetMasks(4)
+	if len(masks) != 15 {
+		t.Fatalf("got %v masks", len(masks))
+	}
+	// lexicographic, base before gap
+	for k := 1; k < len(masks); k++ {
+		prev, cur := masks[k-1], masks[k]
+		i := 0
+		for i < len(cur) && prev[i] == cur[i] {
+			i++
+		}
+		if i == len(cur) || prev[i] != 1 {
+			t.Errorf("%v before %v", prev, cur)
+		}
+	}
+	// ties break the same way every run, pinned so a change shows up here
+	seqStrings := []string{"ACGTTA", "ACTA", "AGTTCA"}
+	want := []string{"ACGTT-A", "AC--T-A", "A-GTTCA"}
+	for run := 0; run < 5; run++ {
+		if got := rowsOf(SolveAlignment(seqStrings, bio.DefaultScoreConfig())); !slices.Equal(got, want) {
+			t.Errorf("run %v: got %v, want %v", run, got, want)
+		}
+	}
+}
+
+func TestSolver(t *testing.T) {
+	s := NewSolver(bio.DefaultScoreConfig())
+	rng := rand.New(rand.NewSource(17))
+	for trial := 0; trial < 40; trial++ {
+		var seqStrings []string
+		for i := 0; i < 2+trial%3; i++ {
+			seqStrings = append(seqStrings, randomSeq(rng, rng.Intn(10)))
+		}
+		if got, want := s.Score(seqStrings), Solve(seqStrings); got != want {
+			t.Errorf("%v: got %v, want %v", seqStrings, got, want)
+		}
+		if trial%10 == 9 {
+			s.Reset()
+		}
+	}
+}
+
+func BenchmarkSolveRepeated(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Solve([]string{x1, x2, x3})
+	}
+}
+
+func BenchmarkSolverReused(b *testing.B) {
+	b.ReportAllocs()
+	s := NewSolver(bio.DefaultScoreConfig())
+	for i := 0; i < b.N; i++ {
+		s.Score([]string{x1, x2, x3})
+	}
+}
+
+func TestSolveWithColumnScorer(t *testing.T) {
+	seqStrings := []string{x1, x2, x3}
+	if got, want := SolveWithColumnScorer(seqStrings, nil), Solve(seqStrings); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	sp := func(bases []bio.Base) int64 { return bio.ColumnSPScore(bases) }
+	if got, want := SolveWithColumnScorer(seqStrings, sp), Solve(seqStrings); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// a consensus score: 2 per base agreeing with the column's majority base
+	consensus := func(bases []bio.Base) int64 {
+		counts := map[bio.Base]int{}
+		for _, b := range bases {
+			if b != bio.X {
+				counts[b]++
+			}
+		}
+		most := 0
+		for _, n := range counts {
+			most = max(most, n)
+		}
+		return int64(2 * most)
+	}
+	if got := SolveWithColumnScorer([]string{"ACGT", "ACGT", "ACGT"}, consensus); got != 12 {
+		t.Errorf("got %v, want 12", got)
+	}
+}
+
+func TestSolveStrandedExhaustive(t *testing.T) {
+	rc := bio.AToSeq("GATTACAGG").ReverseComplement().String()
+	score, reversed := SolveStrandedExhaustive([]string{"GATTACAGG", rc, "GATTACAGG"})
+	if want := Solve([]string{"GATTACAGG", "GATTACAGG", "GATTACAGG"}); score != want {
+		t.Errorf("got %v, want %v", score, want)
+	}
+	if !slices.Equal(reversed, []bool{false, true, false}) {
+		t.Errorf("got %v", reversed)
+	}
+}
+
+func TestSolveObserved(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{"GAT", "GT"}
+	_, scores, _ := SolveDebug(seqStrings, cfg)
+	seen, last := map[string]bool{}, ""
+	score := SolveObserved(seqStrings, cfg, func(idxs []int, s int64) {
+		if s != int64(scores.At(idxs)) {
+			t.Errorf("%v: got %v, want %v", idxs, s, scores.At(idxs))
+		}
+		last = fmt.Sprint(idxs)
+		seen[last] = true
+	})
+	if score != SolveWithConfig(seqStrings, cfg) {
+		t.Error("Incorrect score.")
+	}
+	// every cell off the empty-prefix rows, the last one after all others
+	if len(seen) != 6 || last != "[3 2]" {
+		t.Errorf("got %v, last %v", seen, last)
+	}
+}
+
+func TestFewerThanTwoSequences(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	for _, seqStrings := range [][]string{nil, {}, {""}, {"A"}, {x1}, {"NNRY"}} {
+		if s := Solve(seqStrings); s != 0 {
+			t.Errorf("%q: got %v", seqStrings, s)
+		}
+		if s := SolveGlobal(seqStrings, cfg); s != 0 {
+			t.Errorf("%q: global got %v", seqStrings, s)
+		}
+		if s := NewSolver(cfg).Score(seqStrings); s != 0 {
+			t.Errorf("%q: solver got %v", seqStrings, s)
+		}
+	}
+	// the alignment of one sequence is that sequence
+	a := SolveAlignment([]string{x1}, cfg)
+	checkAlignment(t, a, []string{x1})
+	if a.Score != 0 || a.Width() != len(x1) {
+		t.Errorf("got width %v scoring %v", a.Width(), a.Score)
+	}
+}
+
+func TestGapGap(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{"AAAA", "A", "A"}
+	// A/A/A and three columns A - -, each 2*gap plus one pair of gaps
+	if s := SolveGlobal(seqStrings, cfg); s != 0 {
+		t.Errorf("got %v", s)
+	}
+	cfg.GapGap = -2
+	if s := SolveGlobal(seqStrings, cfg); s != -3 {
+		t.Errorf("got %v", s)
+	}
+	if s := SolveGlobal([]string{"ACGT", "ACGT", "ACGT"}, cfg); s != 36 {
+		t.Errorf("identical: got %v", s)
+	}
+	// a reward for pairs of gaps still never buys a column of only gaps
+	cfg.GapGap = 4
+	a := SolveAlignment([]string{x1, x2, x3}, cfg)
+	checkAlignment(t, a, []string{x1, x2, x3})
+	for c := 0; c < a.Width(); c++ {
+		gaps := 0
+		for _, row := range a.Rows {
+			if row.Bases[c] == bio.X {
+				gaps++
+			}
+		}
+		if gaps == len(a.Rows) {
+			t.Errorf("column %v holds only gaps", c)
+		}
+	}
+}
+
+func TestSolveStream(t *testing.T) {
+	rng := rand.New(rand.NewSource(23))
+	inputs := [][]string{{x1, ""}}
+	for len(inputs) < 20 {
+		inputs = append(inputs, []string{randomSeq(rng, 1+rng.Intn(8)), randomSeq(rng, 1+rng.Intn(8)), randomSeq(rng, 1+rng.Intn(8))})
+	}
+	in := make(chan []string)
+	go func() {
+		for _, seqStrings := range inputs {
+			in <- seqStrings
+		}
+		close(in)
+	}()
+	seen := make([]bool, len(inputs))
+	for r := range SolveStream(context.Background(), in, 4) {
+		seen[r.Index] = true
+		if r.Index == 0 {
+			if !errors.Is(r.Err, ErrEmptySequence) {
+				t.Errorf("expected ErrEmptySequence, got %v", r.Err)
+			}
+			continue
+		}
+		if want := Solve(inputs[r.Index]); r.Err != nil || r.Score != want {
+			t.Errorf("input %v: got %v, %v, want %v", r.Index, r.Score, r.Err, want)
+		}
+	}
+	if slices.Contains(seen, false) {
+		t.Errorf("missing results: %v", seen)
+	}
+	// a cancelled stream closes without reading its input
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for r := range SolveStream(ctx, make(chan []string), 2) {
+		t.Errorf("unexpected result %v", r)
+	}
+}
+
+func TestSolveProfiles(t *testing.T) {
+	a := []string{"AC-GT", "ACCGT"}
+	b := []string{"ACGT"}
+	rows, score := SolveProfiles(a, b)
+	want := []string{"AC-GT", "ACCGT", "AC-GT"}
+	if !slices.Equal(rows, want) {
+		t.Errorf("got %v, want %v", rows, want)
+	}
+	// four columns of two matches, then C against a gap and a pair of
+	// gaps, averaged over the two pairs of rows
+	if expected := bio.ToNatural(4*2*6-3) / 2; score != expected {
+		t.Errorf("got %v, want %v", score, expected)
+	}
+	// a single row per profile is a plain global pairwise alignment
+	if _, s := SolveProfiles([]string{x1}, []string{x2}); s != SolveGlobal([]string{x1, x2}, bio.DefaultScoreConfig()) {
+		t.Error("Incorrect score.")
+	}
+	if rows, _ := SolveProfiles([]string{"AC", "A"}, b); rows != nil {
+		t.Errorf("ragged profile: got %v", rows)
+	}
+}
+
+func TestScoreOverflow(t *testing.T) {
+	// scaling every score by k keeps each value within an int32, but the
+	// optimal scores run past it, so every solver must sum in int64
+	const k = 1 << 28
+	small := bio.DefaultScoreConfig()
+	cfg := bio.ScoreConfig{Match: small.Match * k, Mismatch: small.Mismatch * k, Gap: small.Gap * k}
+	for _, seqStrings := range [][]string{
+		{"ACGTACGT", "ACGTACGT"},
+		{"ACGTACGT", "ACGAACGT"},
+		{"ACGTACGT", "ACGAACGT", "ACGTACGA"},
+		{"ACGTACGT", "A", "C"},
+	} {
+		want := SolveGlobal(seqStrings, small) * k
+		if s := SolveGlobal(seqStrings, cfg); s != want {
+			t.Errorf("%v: got %v, want %v", seqStrings, s, want)
+		}
+		if math.Abs(want) <= math.MaxInt32 {
+			t.Errorf("%v: %v does not overflow an int32", seqStrings, want)
+		}
+	}
+}
+
+func TestBoundsWithGapGap(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	cfg.GapGap = 4
+	// a gap-gap reward lifts the optimum above the sum of the pairwise
+	// optima, so neither solver may use that sum as a bound
+	for _, seqStrings := range [][]string{{"GAAAG", "ATAGT", "AATT"}, {"TGAGC", "CTCT", "CG"}} {
+		exact := SolveWithConfig(seqStrings, cfg)
+		if score, upper := SolveWithin(seqStrings, cfg, 6); score != exact || upper < exact {
+			t.Errorf("%v: got %v, %v, want %v", seqStrings, score, upper, exact)
+		}
+		if lower, upper := ScoreBounds(seqStrings, cfg); lower > exact || !math.IsInf(upper, 1) {
+			t.Errorf("%v: %v not within [%v, %v]", seqStrings, exact, lower, upper)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Package mdphttp exposes the exact multiple alignment solver over HTTP.
+
+A request is a JSON body of the form
+
+	{"sequences": ["AATTATGG", "ACATTGTTG"], "lengthPolicy": 2, "maxRatio": 3}
+
+and the response is the optimal alignment as an msa.AlignmentResult
+
+	{"rows": ["AATTAT-GG", "ACATTGTTG"], "score": 12.5, "exact": true, ...}
+
+lengthPolicy and maxRatio are optional and map to mdp.ApplyLengthPolicy.
+Inputs beyond mdp.MaxSequences or mdp.MaxWork are rejected with 413 before
+solving, and a solve running past Timeout or the request's context is
+stopped and answered with 503.
+*/
+package mdphttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa/mdp"
+)
+
+const (
+	// MaxBodyBytes is the largest request body accepted by the handler.
+	MaxBodyBytes = 1 << 20
+	// Timeout bounds how long the handler solves a request.
+	Timeout = 30 * time.Second
+)
+
+type request struct {
+	Sequences    []string         `json:"sequences"`
+	LengthPolicy mdp.LengthPolicy `json:"lengthPolicy"`
+	MaxRatio     float64          `json:"maxRatio"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns an http.Handler that solves the posted sequences.
+func Handler() http.Handler {
+	return http.HandlerFunc(serveSolve)
+}
+
+func serveSolve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"POST required"})
+		return
+	}
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	if len(req.Sequences) == 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{"no sequences"})
+		return
+	}
+	seqStrings, err := mdp.ApplyLengthPolicy(req.Sequences, req.LengthPolicy, req.MaxRatio)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, errorResponse{err.Error()})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), Timeout)
+	defer cancel()
+
+	// the solve runs on the handler's goroutine and stops once ctx is done
+	a, err := mdp.SolveAlignmentContext(ctx, seqStrings, bio.DefaultScoreConfig())
+	switch {
+	case errors.Is(err, mdp.ErrTooManySequences) || errors.Is(err, mdp.ErrInfeasible):
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, a.Result())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,66 @@
+package mdphttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bsjcho/bioinf/msa"
+)
+
+func TestHandler(t *testing.T) {
+	body := `{"sequences": ["AATTATGG", "ACATTGTTG", "GCCAGGAGG", "AATTTTGAGG"]}`
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %v: %v", rec.Code, rec.Body)
+	}
+	var res msa.AlignmentResult
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Score != 45 || len(res.Rows) != 4 || !res.Exact {
+		t.Error("Incorrect score.")
+	}
+}
+
+func TestHandlerBadRequest(t *testing.T) {
+	for _, body := range []string{`{`, `{"sequences": []}`, `{"sequences": ["AAAAAAAA", "A"], "lengthPolicy": 2, "maxRatio": 2}`} {
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if rec.Code == http.StatusOK {
+			t.Errorf("expected error for %v", body)
+		}
+	}
+}
+
+func TestHandlerLimits(t *testing.T) {
+	body := `{"sequences": ["A", "C", "G", "T", "A", "C"]}`
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status %v, expected %v", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestHandlerCancelled(t *testing.T) {
+	// about 40^4 cells: several seconds to solve, well within MaxWork
+	seq := strings.Repeat("ACGTTGCA", 5)
+	body := `{"sequences": ["` + seq + `", "` + seq[1:] + `", "` + seq[2:] + `", "` + seq[3:] + `"]}`
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)).WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %v, expected %v", rec.Code, http.StatusServiceUnavailable)
+	}
+	// the handler solves synchronously, so returning means the solve stopped
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("handler took %v after its context was cancelled", elapsed)
+	}
+}
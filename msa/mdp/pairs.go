@@ -0,0 +1,49 @@
+package mdp
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveAlignmentPairs is like SolveAlignment but only the listed pairs of
+// sequences contribute to the sum-of-pairs objective. the other sequences
+// are still aligned, as context, but their pairs score nothing, so e.g. an
+// outgroup can be placed without its mismatches steering the alignment of
+// the rest. Score is the restricted objective. pairs must hold distinct
+// indices in range, each unordered pair at most once, and cfg must use the
+// Pairwise gap model, the only one that splits into per-pair scores.
+func SolveAlignmentPairs(seqStrings []string, cfg bio.ScoreConfig, pairs [][2]int) (*msa.Alignment, error) {
+	if cfg.GapModel != bio.Pairwise {
+		return nil, fmt.Errorf("mdp: scored pairs need the Pairwise gap model")
+	}
+	seen := map[[2]int]bool{}
+	for _, p := range pairs {
+		i, j := p[0], p[1]
+		if i < 0 || j < 0 || i >= len(seqStrings) || j >= len(seqStrings) {
+			return nil, fmt.Errorf("mdp: pair %v out of range for %v sequences", p, len(seqStrings))
+		}
+		if i == j {
+			return nil, fmt.Errorf("mdp: pair %v pairs a sequence with itself", p)
+		}
+		if i > j {
+			i, j = j, i
+		}
+		if seen[[2]int{i, j}] {
+			return nil, fmt.Errorf("mdp: pair %v listed twice", p)
+		}
+		seen[[2]int{i, j}] = true
+	}
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	mdp.pairs = pairs
+	return mdp.alignment(nil), nil
+}
+
+// pairsScore sums the scores of m.pairs in a column
+func (m *multiDP) pairsScore(bases []bio.Base) (sum int64) {
+	for _, p := range m.pairs {
+		sum = bio.AddScores(sum, m.cfg.PairScore(bases[p[0]], bases[p[1]]))
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package mdp
+
+import (
+	"runtime"
+	"sync"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// PairwiseResult is the optimal alignment of one pair of input sequences
+type PairwiseResult struct {
+	I, J      int            // indices of the pair in the input, I < J
+	Score     float64        // optimal global score, as SolveGlobal returns it
+	Alignment *msa.Alignment // an optimal global alignment of the pair
+}
+
+// AllPairwise globally aligns every pair of sequences under cfg, solving the
+// pairs in parallel by up to GOMAXPROCS workers. every residue of both
+// sequences is scored, so each column of an alignment pairs what cfg says
+// it pairs. results are ordered by I and then J, so the pair (i, j) is at
+// index i*(2n-i-1)/2 + j-i-1 for n sequences.
+func AllPairwise(seqStrings []string, cfg bio.ScoreConfig) []PairwiseResult {
+	pairs, tables := pairTables(cfg.Sequences(seqStrings), cfg, true)
+	results := make([]PairwiseResult, len(pairs))
+	for k, p := range pairs {
+		a := tables[k].alignment(nil)
+		results[k] = PairwiseResult{I: p[0], J: p[1], Score: a.Score, Alignment: a}
+	}
+	return results
+}
+
+// pairTables returns every pair of seqs in sorted order together with its
+// DP, solved in the given mode over all prefixes. the tables are filled in
+// parallel and only read afterwards, so they can be shared.
+func pairTables(seqs []*bio.Sequence, cfg bio.ScoreConfig, global bool) (pairs [][2]int, tables []*multiDP) {
+	for i := range seqs {
+		for j := i + 1; j < len(seqs); j++ {
+			pair := newMultiDP([]*bio.Sequence{seqs[i], seqs[j]}, cfg)
+			pair.global = global
+			pairs = append(pairs, [2]int{i, j})
+			tables = append(tables, pair)
+		}
+	}
+	var wg sync.WaitGroup
+	work := make(chan *multiDP)
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range work {
+				t.fill(RowMajor)
+			}
+		}()
+	}
+	for _, t := range tables {
+		work <- t
+	}
+	close(work)
+	wg.Wait()
+	return
+}
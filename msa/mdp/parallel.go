@@ -0,0 +1,62 @@
+package mdp
+
+import (
+	"runtime"
+	"sync"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// SolveParallel is like Solve but fills the table one anti-diagonal at a
+// time, spreading the cells of each across workers goroutines, or
+// GOMAXPROCS if workers is below 1. every move decreases the sum of the
+// indices, so the cells of a diagonal only depend on earlier diagonals and
+// can be computed independently. workers only read the table; each returns
+// its scores and the caller stores them once the diagonal is done, so the
+// result is that of the serial solver.
+func SolveParallel(seqStrings []string, workers int) float64 {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	mdp := newMultiDP(bio.AsToSeqs(seqStrings), bio.DefaultScoreConfig())
+	mdp.fillParallel(workers)
+	return mdp.solve()
+}
+
+// fillParallel computes every cell that is not a base case, diagonal by
+// diagonal
+func (m *multiDP) fillParallel(workers int) {
+	var diagonals [][][]int
+	m.eachCell(func(idxs []int) {
+		if m.isBaseCase(idxs) {
+			return
+		}
+		d := 0
+		for _, i := range idxs {
+			d += i
+		}
+		for len(diagonals) <= d {
+			diagonals = append(diagonals, nil)
+		}
+		diagonals[d] = append(diagonals[d], cpy(idxs))
+	})
+	for _, cells := range diagonals {
+		scores := make([]int64, len(cells))
+		var wg sync.WaitGroup
+		for w := 0; w < workers && w < len(cells); w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for c := w; c < len(cells); c += workers {
+					scores[c] = m.bestScore(cells[c])
+				}
+			}(w)
+		}
+		wg.Wait()
+		for c, idxs := range cells {
+			m.table.Set(scores[c], idxs)
+			m.cached.Set(1, idxs)
+			m.cells++
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// SolveProfiles globally aligns two profiles, each a block of gapped rows of
+// equal length, treating every column of a profile as a unit: a column of a
+// is either paired with one of b or set against a column of gaps inserted
+// into b, and the other way round, so each profile keeps its own columns and
+// gaps. a pair of columns scores the average of cfg's pair scores over the
+// pairs of one row of a and one row of b under the default config; every
+// pair of columns holds the same number of such pairs, so this is the sum of
+// pairs across the profiles scaled down. it returns the rows of a followed
+// by those of b and the summed average, or nil and 0 if either profile is
+// empty or ragged.
+func SolveProfiles(a, b []string) ([]string, float64) {
+	pa, pb := profileColumns(a), profileColumns(b)
+	if pa == nil || pb == nil {
+		return nil, 0
+	}
+	cfg := bio.DefaultScoreConfig()
+	profiles := [2][][]bio.Base{pa, pb}
+	gaps := [2][]bio.Base{gapColumn(len(a)), gapColumn(len(b))}
+	// one placeholder sequence per profile, a residue per column
+	mdp := newMultiDP([]*bio.Sequence{placeholder(len(pa)), placeholder(len(pb))}, cfg)
+	mdp.global = true
+	mdp.library = func(idxs, mask []int) int64 {
+		ca, cb := gaps[0], gaps[1]
+		if mask[0] == 1 {
+			ca = pa[idxs[0]-1]
+		}
+		if mask[1] == 1 {
+			cb = pb[idxs[1]-1]
+		}
+		return crossScore(ca, cb, cfg)
+	}
+	aligned := mdp.alignment(nil)
+	sum := mdp.optimalScore(mdp.maxIndices())
+	// expand each placeholder residue back into its profile column
+	var cols [][]bio.Base
+	var next [2]int
+	for c := 0; c < aligned.Width(); c++ {
+		col := make([]bio.Base, 0, len(a)+len(b))
+		for p := range profiles {
+			if aligned.Rows[p].Bases[c] == bio.X {
+				col = append(col, gaps[p]...)
+				continue
+			}
+			col = append(col, profiles[p][next[p]]...)
+			next[p]++
+		}
+		cols = append(cols, col)
+	}
+	rows := make([]string, len(a)+len(b))
+	for i, row := range alignmentFromColumns(cols, len(rows)).Rows {
+		rows[i] = row.String()
+	}
+	return rows, bio.ToNatural(sum) / float64(len(a)*len(b))
+}
+
+// profileColumns returns the columns of the rows of a profile, or nil if
+// there are none or they differ in length
+func profileColumns(rows []string) [][]bio.Base {
+	if len(rows) == 0 {
+		return nil
+	}
+	seqs := bio.AsToSeqs(rows)
+	for _, s := range seqs {
+		if len(s.Bases) != len(seqs[0].Bases) {
+			return nil
+		}
+	}
+	cols := make([][]bio.Base, len(seqs[0].Bases))
+	for c := range cols {
+		for _, s := range seqs {
+			cols[c] = append(cols[c], s.Bases[c])
+		}
+	}
+	return cols
+}
+
+// crossScore sums cfg's pair scores over the pairs of one base of ca and one
+// of cb
+func crossScore(ca, cb []bio.Base, cfg bio.ScoreConfig) (sum int64) {
+	for _, x := range ca {
+		for _, y := range cb {
+			sum = bio.AddScores(sum, cfg.PairScore(x, y))
+		}
+	}
+	return
+}
+
+// placeholder returns a sequence of n bases standing for n profile columns
+func placeholder(n int) *bio.Sequence {
+	s := bio.NewSequence()
+	for i := 0; i < n; i++ {
+		s.Bases = append(s.Bases, bio.A)
+	}
+	return s
+}
+
+// gapColumn returns a column of n gaps
+func gapColumn(n int) []bio.Base {
+	col := make([]bio.Base, n)
+	for i := range col {
+		col[i] = bio.X
+	}
+	return col
+}
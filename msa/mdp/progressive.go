@@ -0,0 +1,52 @@
+package mdp
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveProgressive aligns any number of sequences approximately under the
+// default config, for inputs far beyond what the exact solver can handle.
+// Every pair is scored with the two-row DP of SolveSequences, the scores are
+// turned into Identity distances against the smaller self score, and
+// msa.GuidedProgressive aligns along the resulting guide tree. It returns
+// the gapped rows in input order and their sum-of-pairs score, or
+// ErrNoSequences for an empty input.
+func SolveProgressive(seqStrings []string) ([]string, float64, error) {
+	if len(seqStrings) == 0 {
+		return nil, 0, ErrNoSequences
+	}
+	cfg := bio.DefaultScoreConfig()
+	seqs := cfg.Sequences(seqStrings)
+	self := make([]float64, len(seqs))
+	for i, s := range seqs {
+		self[i] = bio.ToNatural(bio.MulScores(int64(cfg.Match), int64(len(s.Bases))))
+	}
+	dm := make([][]float64, len(seqs))
+	for i := range dm {
+		dm[i] = make([]float64, len(seqs))
+	}
+	for i := range seqs {
+		for j := i + 1; j < len(seqs); j++ {
+			pair := &multiDP{seqs: []*bio.Sequence{seqs[i], seqs[j]}, cfg: cfg, unit: 1}
+			top := math.Min(self[i], self[j])
+			d := msa.ConvertDistance(math.Min(bio.ToNatural(pair.twoScore()), top), top, msa.Identity)
+			if math.IsNaN(d) {
+				// an empty sequence has no identity to measure
+				d = 1
+			}
+			dm[i][j], dm[j][i] = d, d
+		}
+	}
+	a, err := msa.GuidedProgressive(seqStrings, dm, cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows := make([]string, len(a.Rows))
+	for i, row := range a.Rows {
+		rows[i] = row.String()
+	}
+	return rows, a.Score, nil
+}
@@ -0,0 +1,103 @@
+package mdp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// Insertion holds residues of one sequence that have no reference residue
+// to align to. they belong between columns Pos-1 and Pos of the
+// reference-coordinate alignment: Pos 0 is before the first column and Pos
+// equal to the width after the last.
+type Insertion struct {
+	Seq   int        // index of the inserted sequence
+	Pos   int        // column the residues precede
+	Bases []bio.Base // the inserted residues, in order
+}
+
+// SolveFixedReference is like SolveAlignment but the sequence at index ref
+// is never gapped: every column holds one of its residues, so column i of the
+// result is position i of the reference. the other sequences are aligned to
+// it freely, and every column of the optimal alignment that gaps the
+// reference is taken out and recorded in insertions instead, as are the
+// leading residues that precede the reference start. each residue of the
+// input is thus either in a column or in an insertion. insertions are
+// ordered by Pos and then Seq. Score is that of the returned columns under
+// cfg, without the insertions. taking columns out of an optimal alignment
+// does not leave an optimal one, so the result is not Exact.
+func SolveFixedReference(seqStrings []string, ref int, cfg bio.ScoreConfig) (a *msa.Alignment, insertions []Insertion, err error) {
+	if ref < 0 || ref >= len(seqStrings) {
+		return nil, nil, fmt.Errorf("mdp: reference index %v out of range for %v sequences", ref, len(seqStrings))
+	}
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	mdp.solve() // fills the table the traceback walks
+	cols, idxs := mdp.traceback(nil)
+	kept, clipped := mdp.referenceColumns(idxs, ref)
+	for i, n := range clipped {
+		if n > 0 {
+			insertions = append(insertions, Insertion{Seq: i, Bases: slices.Clone(mdp.seqs[i].Bases[:n])})
+		}
+	}
+	for _, col := range cols {
+		if col[ref] != bio.X {
+			kept = append(kept, col)
+			continue
+		}
+		for i, b := range col {
+			if b != bio.X {
+				insertions = addInsertion(insertions, i, len(kept), b)
+			}
+		}
+	}
+	sort.SliceStable(insertions, func(i, j int) bool {
+		if insertions[i].Pos != insertions[j].Pos {
+			return insertions[i].Pos < insertions[j].Pos
+		}
+		return insertions[i].Seq < insertions[j].Seq
+	})
+	a = alignmentFromColumns(kept, len(seqStrings))
+	a.Rescore(cfg)
+	return a, insertions, nil
+}
+
+// addInsertion appends b to the insertion of sequence seq before column pos,
+// starting one if there is none. insertions arrive in column order, so only
+// those at pos, at the end of the list, need to be searched.
+func addInsertion(insertions []Insertion, seq, pos int, b bio.Base) []Insertion {
+	for k := len(insertions) - 1; k >= 0 && insertions[k].Pos == pos; k-- {
+		if insertions[k].Seq == seq {
+			insertions[k].Bases = append(insertions[k].Bases, b)
+			return insertions
+		}
+	}
+	return append(insertions, Insertion{Seq: seq, Pos: pos, Bases: []bio.Base{b}})
+}
+
+// referenceColumns is leadingColumns for a fixed reference: the reference's
+// unscored prefix sets the width, and each other prefix is right-justified
+// under it with the number of leading residues that do not fit in clipped.
+func (m *multiDP) referenceColumns(idxs []int, ref int) (cols [][]bio.Base, clipped []int) {
+	width := idxs[ref]
+	clipped = make([]int, len(idxs))
+	for i, idx := range idxs {
+		if idx > width {
+			clipped[i] = idx - width
+		}
+	}
+	for c := 0; c < width; c++ {
+		col := make([]bio.Base, len(idxs))
+		for i, idx := range idxs {
+			if pos := c - (width - idx); pos >= 0 {
+				col[i] = m.seqs[i].Bases[pos]
+			} else {
+				col[i] = bio.X
+			}
+		}
+		cols = append(cols, col)
+	}
+	return
+}
@@ -0,0 +1,47 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// reset prepares m to solve seqs without reallocating its tables. seqs must
+// fit the tables: as many sequences, each no longer than the tables allow,
+// see Solver. only the cached flags of the cells seqs use are cleared; table
+// entries are left in place since they are only read once their cached flag
+// is set.
+func (m *multiDP) reset(seqs []*bio.Sequence) {
+	m.seqs = seqs
+	m.cells = 0
+	m.zeroCached()
+}
+
+// zeroCached clears every cached flag in place
+func (m *multiDP) zeroCached() {
+	if s, ok := m.cached.(sparseStore); ok {
+		clear(s)
+		return
+	}
+	m.eachCell(func(idxs []int) {
+		m.cached.Set(0, idxs)
+	})
+}
+
+// eachCell calls fn with every index tuple of the table in row-major order.
+// fn must not keep or modify idxs.
+func (m *multiDP) eachCell(fn func(idxs []int)) {
+	dims := m.dims()
+	idxs := make([]int, len(dims))
+	for {
+		fn(idxs)
+		// advance idxs like an odometer over dims
+		i := len(idxs) - 1
+		for ; i >= 0; i-- {
+			idxs[i]++
+			if idxs[i] < dims[i] {
+				break
+			}
+			idxs[i] = 0
+		}
+		if i < 0 {
+			return
+		}
+	}
+}
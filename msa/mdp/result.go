@@ -0,0 +1,20 @@
+package mdp
+
+import (
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveResult aligns the sequences as SolveAlignmentE does under the default
+// config and returns the alignment as an msa.AlignmentResult, ready for
+// encoding/json. it returns ErrNoSequences for an empty input.
+func SolveResult(seqStrings []string) (msa.AlignmentResult, error) {
+	if len(seqStrings) == 0 {
+		return msa.AlignmentResult{}, ErrNoSequences
+	}
+	a, err := SolveAlignmentE(seqStrings, bio.DefaultScoreConfig())
+	if err != nil {
+		return msa.AlignmentResult{}, err
+	}
+	return a.Result(), nil
+}
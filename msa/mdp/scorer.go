@@ -0,0 +1,18 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// ColumnScorer scores a column of bases, gaps included, on the doubled
+// scale. like the built-in column score it must not depend on where the
+// column lies, and a column of only gaps should score 0 so that padding an
+// alignment with one leaves its score unchanged.
+type ColumnScorer func(bases []bio.Base) int64
+
+// SolveWithColumnScorer is like Solve but scores every column with cs in
+// place of the sum-of-pairs score, e.g. to try entropy or consensus based
+// objectives. a nil cs scores sum-of-pairs under the default config.
+func SolveWithColumnScorer(seqStrings []string, cs ColumnScorer) float64 {
+	mdp := newMultiDP(bio.AsToSeqs(seqStrings), bio.DefaultScoreConfig())
+	mdp.column = cs
+	return mdp.solve()
+}
@@ -0,0 +1,70 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// Solver scores many independent inputs under one config, like
+// SolveSequences, but keeps its tables between calls. a table serves any
+// later input of as many sequences that fits inside it, after clearing the
+// cached flags of the cells the input uses, and grows to cover both when an
+// input doesn't fit, so a stream of similar inputs allocates once. a Solver
+// is not safe for concurrent use.
+type Solver struct {
+	cfg  bio.ScoreConfig
+	m    *multiDP
+	dims []int // dimensions the tables were allocated with
+}
+
+// NewSolver returns a Solver scoring under cfg
+func NewSolver(cfg bio.ScoreConfig) *Solver {
+	return &Solver{cfg: cfg}
+}
+
+// Reset drops the tables, so their memory can be reclaimed, e.g. after an
+// unusually large input
+func (s *Solver) Reset() {
+	s.m, s.dims = nil, nil
+}
+
+// Score returns the score SolveWithConfig gives for seqStrings under the
+// Solver's config
+func (s *Solver) Score(seqStrings []string) float64 {
+	seqs := bio.AsToSeqs(seqStrings)
+	if len(seqs) < 2 {
+		return 0
+	}
+	if score, ok := identicalScore(seqs, s.cfg); ok {
+		return bio.ToNatural(score)
+	}
+	if len(seqs) == 2 && !s.cfg.TerminalGapFree {
+		return bio.ToNatural((&multiDP{seqs: seqs, cfg: s.cfg, unit: 1}).twoScore())
+	}
+	s.prepare(seqs)
+	return s.m.solve()
+}
+
+// prepare points s.m at seqs, reusing its tables when they are large enough
+func (s *Solver) prepare(seqs []*bio.Sequence) {
+	need := (&multiDP{seqs: seqs, unit: 1}).dims()
+	fits := s.m != nil && len(need) == len(s.dims)
+	for i := 0; fits && i < len(need); i++ {
+		fits = need[i] <= s.dims[i]
+	}
+	if fits {
+		s.m.reset(seqs)
+		return
+	}
+	if len(need) == len(s.dims) {
+		for i := range need {
+			need[i] = max(need[i], s.dims[i])
+		}
+	}
+	s.dims = need
+	s.m = &multiDP{
+		seqs:        seqs,
+		cfg:         s.cfg,
+		unit:        1,
+		subsetMasks: generateSubsetMasks(len(seqs)),
+		table:       newStore(need),
+		cached:      newStore(need),
+	}
+}
@@ -0,0 +1,38 @@
+package mdp
+
+import (
+	"time"
+	"unsafe"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// SolveStats describes the work done by one solve.
+type SolveStats struct {
+	Elapsed       time.Duration // wall time spent filling the table
+	CellsComputed int64         // cells whose score was computed
+	CellsTotal    int64         // cells in the table, the product of len+1
+	PeakBytes     int64         // estimated size of the score and cached tables
+}
+
+// SolveWithStats is like SolveWithConfig but also reports statistics about
+// the solve. only the cells reachable from the final cell are computed, so
+// CellsComputed may be less than CellsTotal. PeakBytes is derived from the
+// table dimensions, assuming one int64 per cell in each of the score and
+// cached tables, not measured. above DenseLimit cells the tables are sparse
+// and hold only the cells written, so there it is an upper bound.
+func SolveWithStats(seqStrings []string, cfg bio.ScoreConfig) (float64, SolveStats) {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	start := time.Now()
+	score := mdp.solve()
+	stats := SolveStats{
+		Elapsed:       time.Since(start),
+		CellsComputed: mdp.cells,
+		CellsTotal:    1,
+	}
+	for _, size := range mdp.dims() {
+		stats.CellsTotal *= int64(size)
+	}
+	stats.PeakBytes = 2 * stats.CellsTotal * int64(unsafe.Sizeof(int64(0)))
+	return score, stats
+}
@@ -0,0 +1,81 @@
+package mdp
+
+import "encoding/binary"
+
+// DenseLimit is the largest number of cells a table is allocated densely
+// for. larger tables are kept in a map holding only the cells written, so
+// a solve whose moves are restricted, e.g. by SolveConstrained, only pays
+// for the cells it reaches; an unrestricted solve reaches every cell either
+// way and is slower in a map.
+var DenseLimit = 1 << 24
+
+// store holds an int64 per index tuple of a table, 0 until set. scores are
+// int64 on every GOARCH, so the dense store is a flat []int64 rather than an
+// nd.Array, whose ints are 32 bits wide on 32-bit builds.
+type store interface {
+	At(idxs []int) int64
+	Set(val int64, idxs []int)
+}
+
+// newStore returns a dense store over dims, or a sparse one if it would
+// have more than DenseLimit cells
+func newStore(dims []int) store {
+	size := 1
+	for _, d := range dims {
+		if size > DenseLimit/d {
+			return sparseStore{}
+		}
+		size *= d
+	}
+	return newDenseStore(dims, size)
+}
+
+// denseStore is a store over a flat slice in row-major order
+type denseStore struct {
+	strides []int
+	data    []int64
+}
+
+func newDenseStore(dims []int, size int) *denseStore {
+	d := &denseStore{strides: make([]int, len(dims)), data: make([]int64, size)}
+	stride := 1
+	for i := len(dims) - 1; i >= 0; i-- {
+		d.strides[i] = stride
+		stride *= dims[i]
+	}
+	return d
+}
+
+func (d *denseStore) offset(idxs []int) (o int) {
+	for i, idx := range idxs {
+		o += idx * d.strides[i]
+	}
+	return
+}
+
+func (d *denseStore) At(idxs []int) int64 {
+	return d.data[d.offset(idxs)]
+}
+
+func (d *denseStore) Set(val int64, idxs []int) {
+	d.data[d.offset(idxs)] = val
+}
+
+// sparseStore is a store keyed by the varint encoding of the index tuple
+type sparseStore map[string]int64
+
+func (s sparseStore) At(idxs []int) int64 {
+	return s[sparseKey(idxs)]
+}
+
+func (s sparseStore) Set(val int64, idxs []int) {
+	s[sparseKey(idxs)] = val
+}
+
+func sparseKey(idxs []int) string {
+	key := make([]byte, 0, 2*len(idxs))
+	for _, i := range idxs {
+		key = binary.AppendUvarint(key, uint64(i))
+	}
+	return string(key)
+}
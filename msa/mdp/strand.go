@@ -0,0 +1,68 @@
+package mdp
+
+import (
+	"fmt"
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveStranded aligns sequences of unknown strand. the first sequence is
+// kept as given; every other one is compared with it in both orientations
+// by a pairwise solve under cfg and its reverse complement is used if that
+// scores strictly higher. the chosen orientations are then aligned like
+// SolveAlignment. reversed[i] reports whether sequence i appears reverse
+// complemented in the alignment. like SolveE it rejects more than
+// MaxSequences sequences.
+func SolveStranded(seqStrings []string, cfg bio.ScoreConfig) (a *msa.Alignment, reversed []bool, err error) {
+	if len(seqStrings) == 0 {
+		return nil, nil, ErrNoSequences
+	}
+	if len(seqStrings) > MaxSequences {
+		return nil, nil, fmt.Errorf("%w: %v sequences, MaxSequences is %v", ErrTooManySequences, len(seqStrings), MaxSequences)
+	}
+	seqs := bio.AsToSeqs(seqStrings)
+	reversed = make([]bool, len(seqs))
+	for i, seq := range seqs[1:] {
+		rc := seq.ReverseComplement()
+		forward := SolveSequences([]*bio.Sequence{seqs[0], seq}, cfg)
+		if SolveSequences([]*bio.Sequence{seqs[0], rc}, cfg) > forward {
+			seqs[i+1], reversed[i+1] = rc, true
+		}
+	}
+	a = newMultiDP(seqs, cfg).alignment(nil)
+	// the strands are picked heuristically
+	a.Exact = false
+	return a, reversed, nil
+}
+
+// SolveStrandedExhaustive is the exact counterpart of SolveStranded's
+// orientation search: it solves every combination of orientations of the
+// sequences after the first under the default config and returns the best
+// score with the orientations that reach it, preferring forward strands on
+// ties. the first sequence is kept as given since reverse complementing the
+// whole input only mirrors the alignment. it solves 2^(n-1) alignments of n
+// sequences, so it is meant for a handful of reads.
+func SolveStrandedExhaustive(seqStrings []string) (best float64, reversed []bool) {
+	seqs := bio.AsToSeqs(seqStrings)
+	cfg := bio.DefaultScoreConfig()
+	best = math.Inf(-1)
+	oriented := make([]*bio.Sequence, len(seqs))
+	for flips := 0; flips < 1<<max(len(seqs)-1, 0); flips++ {
+		copy(oriented, seqs)
+		for i := 1; i < len(seqs); i++ {
+			if flips&(1<<(i-1)) != 0 {
+				oriented[i] = seqs[i].ReverseComplement()
+			}
+		}
+		if score := SolveSequences(oriented, cfg); score > best {
+			best = score
+			reversed = make([]bool, len(seqs))
+			for i := 1; i < len(seqs); i++ {
+				reversed[i] = flips&(1<<(i-1)) != 0
+			}
+		}
+	}
+	return best, reversed
+}
@@ -0,0 +1,85 @@
+package mdp
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Result is the outcome of one input of SolveStream: Index is the position
+// of the input on the channel, counting from 0, and Err is set instead of
+// Score when the input was rejected or its solve was cancelled.
+type Result struct {
+	Index int
+	Score float64
+	Err   error
+}
+
+// SolveStream scores each set of sequences received on in, up to workers at
+// a time, or GOMAXPROCS if workers is below 1, and sends a Result for each
+// on the returned channel in whatever order they finish. every input is
+// checked like SolveE and solved like SolveContext on its own tables, so the
+// jobs share no state. the returned channel is closed once in is closed and
+// drained, or once ctx is done; inputs not yet started by then are dropped
+// without a Result.
+func SolveStream(ctx context.Context, in <-chan []string, workers int) <-chan Result {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	type job struct {
+		index      int
+		seqStrings []string
+	}
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := 0; ; i++ {
+			var seqStrings []string
+			var ok bool
+			select {
+			case seqStrings, ok = <-in:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- job{i, seqStrings}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	out := make(chan Result)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r := Result{Index: j.index}
+				r.Score, r.Err = solveStreamed(ctx, j.seqStrings)
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// solveStreamed solves one input of SolveStream
+func solveStreamed(ctx context.Context, seqStrings []string) (float64, error) {
+	kept, _, err := checkSequences(seqStrings)
+	if err != nil || len(kept) == 0 {
+		return 0, err
+	}
+	return SolveContext(ctx, kept)
+}
@@ -0,0 +1,131 @@
+package mdp
+
+import (
+	"math/rand"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveAlignment returns an optimal alignment of the sequences under cfg,
+// using the same objective as SolveWithConfig. when several columns tie
+// during traceback the first mask in subsetMasks order is taken, the one
+// placing a base in the earliest sequence where they differ (see
+// generateSubsetMasks), so the result is deterministic.
+// because partial scores are floored at zero and exhausted sequences are free
+// (see Solve), residues before the point where the optimal path starts are
+// placed, right-justified, in leading columns that do not contribute to
+// Score.
+func SolveAlignment(seqStrings []string, cfg bio.ScoreConfig) *msa.Alignment {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	return mdp.alignment(nil)
+}
+
+// SolveAlignmentRand is like SolveAlignment but picks uniformly at random
+// among tied columns using r. with a fixed seed the result is reproducible;
+// across seeds it samples co-optimal alignments.
+func SolveAlignmentRand(seqStrings []string, cfg bio.ScoreConfig, r *rand.Rand) *msa.Alignment {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	return mdp.alignment(r)
+}
+
+// alignment fills the table and walks back from the max indices. r may be nil.
+// the result is marked Exact, callers adding heuristics must clear it.
+func (m *multiDP) alignment(r *rand.Rand) *msa.Alignment {
+	score := m.solve()
+	cols, idxs := m.traceback(r)
+	a := alignmentFromColumns(append(m.leadingColumns(idxs), cols...), len(m.seqs))
+	a.Score, a.Exact = score, true
+	return a
+}
+
+// traceback walks back from the max indices of a solved table and returns the
+// scored columns in order together with the index tuple where the path starts.
+// r may be nil.
+func (m *multiDP) traceback(r *rand.Rand) (cols [][]bio.Base, idxs []int) {
+	idxs = m.maxIndices()
+	for !m.isBaseCase(idxs) {
+		ties := m.OptimalMoves(idxs)
+		if len(ties) == 0 {
+			// the cell was floored at zero, the path starts here
+			break
+		}
+		mask := ties[0]
+		switch {
+		case r != nil:
+			mask = ties[r.Intn(len(ties))]
+		case m.lengths != nil:
+			mask = m.compactMove(idxs, ties)
+		}
+		moved := m.moveColumns(idxs, mask)
+		for k := len(moved) - 1; k >= 0; k-- {
+			cols = append(cols, moved[k])
+		}
+		idxs, _ = maskedIdxs(idxs, mask)
+	}
+	// collected back to front
+	for i, j := 0, len(cols)-1; i < j; i, j = i+1, j-1 {
+		cols[i], cols[j] = cols[j], cols[i]
+	}
+	return
+}
+
+// alignmentFromColumns transposes cols into the n rows of an unscored
+// alignment
+func alignmentFromColumns(cols [][]bio.Base, n int) *msa.Alignment {
+	a := &msa.Alignment{}
+	for i := 0; i < n; i++ {
+		row := bio.NewSequence()
+		for _, col := range cols {
+			row.Bases = append(row.Bases, col[i])
+		}
+		a.Rows = append(a.Rows, row)
+	}
+	return a
+}
+
+// OptimalFinalMoves solves the sequences under cfg and returns every mask
+// achieving the optimal score at the final cell, i.e. all co-optimal choices
+// for the last alignment column, in subsetMasks order.
+func OptimalFinalMoves(seqStrings []string, cfg bio.ScoreConfig) [][]int {
+	mdp := newMultiDP(cfg.Sequences(seqStrings), cfg)
+	mdp.solve()
+	return mdp.OptimalMoves(mdp.maxIndices())
+}
+
+// OptimalMoves returns the masks whose column reaches the optimal score of
+// idxs, in subsetMasks order. the cell must already be solved; the scores of
+// its predecessors are read from the table rather than recomputed. a cell
+// whose score was floored at zero with no mask reaching it has no moves.
+func (m *multiDP) OptimalMoves(idxs []int) (masks [][]int) {
+	best := m.optimalScore(idxs)
+	for _, mask := range m.subsetMasks {
+		mIdxs, ok := maskedIdxs(idxs, mask)
+		if !ok || (m.allowed != nil && !m.allowed(idxs, mask)) {
+			continue
+		}
+		score := m.moveScore(idxs, mask)
+		if bio.AddScores(m.optimalScore(mIdxs), score) == best {
+			masks = append(masks, mask)
+		}
+	}
+	return
+}
+
+// leadingColumns lays out the unscored prefixes ending at idxs, right-justified
+// so that they abut the first scored column.
+func (m *multiDP) leadingColumns(idxs []int) (cols [][]bio.Base) {
+	width := bio.Max(append([]int{0}, idxs...)...) * m.unit
+	for c := 0; c < width; c++ {
+		col := make([]bio.Base, len(idxs))
+		for i, idx := range idxs {
+			if pos := c - (width - idx*m.unit); pos >= 0 {
+				col[i] = m.seqs[i].Bases[pos]
+			} else {
+				col[i] = bio.X
+			}
+		}
+		cols = append(cols, col)
+	}
+	return
+}
@@ -0,0 +1,35 @@
+package mdp
+
+import bio "github.com/bsjcho/bioinf"
+
+// twoScore returns the doubled optimal score of m's two sequences by plain
+// Needleman-Wunsch over a [][]int, in m's mode, without the masks and nd
+// indexing of optimalScore, and only keeps two rows. for a plain DP, without
+// pairs, a matrix, TerminalGapFree or any other hook, it gives the score optimalScore gives
+// at the max indices; SolveSequences and SolveGlobal use it for pairs. m
+// needs no tables.
+func (m *multiDP) twoScore() int64 {
+	a, b := m.seqs[0].Bases, m.seqs[1].Bases
+	start := int64(0)
+	if m.global {
+		start = bio.Forbidden
+	}
+	prev, cur := make([]int64, len(b)+1), make([]int64, len(b)+1)
+	for j := 1; j <= len(b) && m.global; j++ {
+		prev[j] = bio.AddScores(prev[j-1], m.score([]bio.Base{bio.X, b[j-1]}))
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = 0
+		if m.global {
+			cur[0] = bio.AddScores(prev[0], m.score([]bio.Base{a[i-1], bio.X}))
+		}
+		for j := 1; j <= len(b); j++ {
+			cur[j] = bio.MaxScore(start,
+				bio.AddScores(prev[j-1], m.score([]bio.Base{a[i-1], b[j-1]})),
+				bio.AddScores(prev[j], m.score([]bio.Base{a[i-1], bio.X})),
+				bio.AddScores(cur[j-1], m.score([]bio.Base{bio.X, b[j-1]})))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,48 @@
+package mdp
+
+import (
+	"fmt"
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// weightScale is the resolution of sequence weights: each is rounded to the
+// nearest multiple of 1/weightScale, so a pair's weight is an integer
+// multiple of 1/weightScale² and the DP stays in integers
+const weightScale = 1000
+
+// SolveWeighted is like Solve but each pair of sequences i, j contributes
+// weights[i]*weights[j] times its pair score, so redundant sequences can be
+// down-weighted, e.g. with msa.TreeWeights. every pair is scored, gaps
+// included, as under the Pairwise gap model. weights are rounded to three
+// decimal places before solving, and the result is exact for the rounded
+// weights. it is an error for len(weights) not to match the number of
+// sequences or for a weight to be negative.
+func SolveWeighted(seqStrings []string, weights []float64) (float64, error) {
+	if len(weights) != len(seqStrings) {
+		return 0, fmt.Errorf("mdp: %v weights for %v sequences", len(weights), len(seqStrings))
+	}
+	mdp := newMultiDP(bio.AsToSeqs(seqStrings), bio.DefaultScoreConfig())
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return 0, fmt.Errorf("mdp: invalid weight %v for sequence %v", w, i)
+		}
+		mdp.weights = append(mdp.weights, int(math.Round(w*weightScale)))
+	}
+	return mdp.solve() / (weightScale * weightScale), nil
+}
+
+// weightedScore is the column score under m.weights, scaled by weightScale²
+func (m *multiDP) weightedScore(bases []bio.Base) (sum int64) {
+	for i, bi := range bases {
+		for j := i + 1; j < len(bases); j++ {
+			s := m.cfg.PairScore(bi, bases[j])
+			if s == bio.Forbidden {
+				return bio.Forbidden
+			}
+			sum = bio.AddScores(sum, bio.MulScores(int64(m.weights[i]), bio.MulScores(int64(m.weights[j]), s)))
+		}
+	}
+	return
+}
@@ -0,0 +1,37 @@
+package mdp
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/msa"
+)
+
+// SolveWindow aligns only a window of each sequence, as SolveAlignment
+// does: windows[i] holds the half-open [start, end) residue range of
+// sequence i, which must be in bounds and non-empty. the rows of the
+// alignment are the windowed slices; positions gives, for every row and
+// column, the position of the residue in the original sequence, or -1 for a
+// gap (msa.Alignment.AnnotatedRows shifted by the window start).
+func SolveWindow(seqStrings []string, windows [][2]int, cfg bio.ScoreConfig) (a *msa.Alignment, positions [][]int, err error) {
+	if len(windows) != len(seqStrings) {
+		return nil, nil, fmt.Errorf("mdp: %v windows for %v sequences", len(windows), len(seqStrings))
+	}
+	seqs := cfg.Sequences(seqStrings)
+	for i, w := range windows {
+		if w[0] < 0 || w[0] >= w[1] || w[1] > len(seqs[i].Bases) {
+			return nil, nil, fmt.Errorf("mdp: window %v of sequence %v is empty or outside its %v residues", w, i, len(seqs[i].Bases))
+		}
+		seqs[i].Bases = seqs[i].Bases[w[0]:w[1]]
+	}
+	a = newMultiDP(seqs, cfg).alignment(nil)
+	positions = a.AnnotatedRows()
+	for i, row := range positions {
+		for col, pos := range row {
+			if pos >= 0 {
+				row[col] = pos + windows[i][0]
+			}
+		}
+	}
+	return a, positions, nil
+}
@@ -0,0 +1,916 @@
+package msa
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+func TestTreeWeights(t *testing.T) {
+	// sequences 0 and 1 are near duplicates, 2 is distant from both
+	dm := [][]float64{
+		{0, 0.1, 0.8},
+		{0.1, 0, 0.8},
+		{0.8, 0.8, 0},
+	}
+	w := TreeWeights(dm)
+	t.Log(w)
+	var sum float64
+	for _, x := range w {
+		sum += x
+	}
+	if math.Abs(sum-3) > 1e-9 {
+		t.Errorf("weights should sum to 3, got %v", sum)
+	}
+	if w[0] != w[1] || w[0] >= w[2] {
+		t.Errorf("redundant sequences not down-weighted: %v", w)
+	}
+	for _, x := range TreeWeights([][]float64{{0, 0}, {0, 0}}) {
+		if x != 1 {
+			t.Error("identical sequences should get equal weights")
+		}
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	// additive distances of the tree ((a:1,b:2):1,c:3,d:4)
+	dm := [][]float64{
+		{0, 3, 5, 6},
+		{3, 0, 6, 7},
+		{5, 6, 0, 7},
+		{6, 7, 7, 0},
+	}
+	tree := BuildTree(dm, []string{"a", "b", "c", "d"})
+	nw := tree.Newick()
+	t.Log(nw)
+	if nw != "((a:1,b:2):1,c:3,d:4);" {
+		t.Error("Incorrect tree.")
+	}
+	if nw := BuildTree([][]float64{{0, 2}, {2, 0}}, nil).Newick(); nw != "(0:1,1:1);" {
+		t.Errorf("two leaves: got %v", nw)
+	}
+	if nw := BuildTree([][]float64{{0}}, []string{"x y"}).Newick(); nw != "'x y';" {
+		t.Errorf("one leaf: got %v", nw)
+	}
+}
+
+func TestConvertDistance(t *testing.T) {
+	if d := ConvertDistance(8, 10, Identity); math.Abs(d-0.2) > 1e-9 {
+		t.Errorf("identity: got %v", d)
+	}
+	if d := ConvertDistance(8, 10, JukesCantor); math.Abs(d+0.75*math.Log(1-0.8/3)) > 1e-9 || d <= 0.2 {
+		t.Errorf("jukes-cantor: got %v", d)
+	}
+	if d := ConvertDistance(10, 10, JukesCantor); d != 0 {
+		t.Errorf("identical: got %v", d)
+	}
+	if d := ConvertDistance(2, 10, JukesCantor); !math.IsInf(d, 1) {
+		t.Errorf("saturated: got %v", d)
+	}
+	if d := ConvertDistance(11, 10, Identity); !math.IsNaN(d) {
+		t.Errorf("out of range: got %v", d)
+	}
+}
+
+func TestConservationTrack(t *testing.T) {
+	a, err := NewAlignment(nil, []string{
+		"AC-T",
+		"AG-T",
+		"ATGT",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []int{9, 3, 3, 9}
+	for i, v := range a.ConservationTrack() {
+		if v != expected[i] {
+			t.Errorf("column %v: got %v, expected %v", i, v, expected[i])
+		}
+	}
+	if line := TrackLine(a.ConservationTrack()); line != "9339" {
+		t.Errorf("track line: got %q", line)
+	}
+	if _, err := NewAlignment(nil, []string{"AC", "A"}); err == nil {
+		t.Error("expected error for ragged rows")
+	}
+	if _, err := NewAlignment(nil, []string{"AAC", "A C"}); err == nil {
+		t.Error("expected error for rows that differ once parsed")
+	}
+}
+
+func TestReliabilityScores(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	a, _ := NewAlignment(nil, []string{"ACGTAC", "ACGTAC", "ACGTAC"})
+	if r := a.ReliabilityScores(cfg); fmt.Sprint(r) != "[9 9 9 9 9 9]" {
+		t.Errorf("got %v", r)
+	}
+	// the last row is shifted one column against where every pairwise
+	// alignment puts it
+	b, _ := NewAlignment(nil, []string{"ACGTAC-", "ACGTAC-", "-ACGTAC"})
+	r := b.ReliabilityScores(cfg)
+	t.Log(r)
+	if fmt.Sprint(r) != "[9 3 3 3 3 3 0]" {
+		t.Error("Incorrect reliability.")
+	}
+}
+
+func TestTrimTerminal(t *testing.T) {
+	a, _ := NewAlignment([]string{"x", "y", "z"}, []string{
+		"--ACGT-T--",
+		"-AACGT-TG-",
+		"--ACCTTTG-",
+	})
+	cfg := bio.DefaultScoreConfig()
+	trimmed := a.TrimTerminal(0.5, cfg)
+	if trimmed.Width() != 7 {
+		t.Fatalf("expected 7 columns, got %v", trimmed.Width())
+	}
+	expected, _ := NewAlignment(nil, []string{"ACGT-T-", "ACGT-TG", "ACCTTTG"})
+	if trimmed.Score != expected.Score {
+		t.Errorf("score not recomputed: %v != %v", trimmed.Score, expected.Score)
+	}
+	// the trimmed columns are scored under the scheme passed in
+	edit := bio.EditDistanceConfig()
+	want, _ := ScoreAlignment([]string{"ACGT-T-", "ACGT-TG", "ACCTTTG"}, edit)
+	if s := a.TrimTerminal(0.5, edit).Score; s != want || s == trimmed.Score {
+		t.Errorf("edit distance: got %v, want %v", s, want)
+	}
+	if a.TrimTerminal(0, cfg).Width() != 6 {
+		t.Error("interior gapped columns should stop trimming")
+	}
+}
+
+func TestColumns(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"AC-T", "AG-T"})
+	var cols [][]bio.Base
+	a.Columns(func(col []bio.Base) bool {
+		cols = append(cols, col)
+		return len(cols) < 3
+	})
+	if len(cols) != 3 || cols[1][0] != bio.C || cols[1][1] != bio.G || cols[2][0] != bio.X {
+		t.Errorf("unexpected columns %v", cols)
+	}
+	gaps := 0
+	a.SharedColumns(func(col []bio.Base) bool {
+		for _, b := range col {
+			if b == bio.X {
+				gaps++
+			}
+		}
+		return true
+	})
+	if gaps != 2 {
+		t.Errorf("expected 2 gaps, got %v", gaps)
+	}
+}
+
+func TestAppendColumns(t *testing.T) {
+	a, _ := NewAlignment([]string{"x", "y"}, []string{"AC-G", "ACTG"})
+	b, _ := NewAlignment([]string{"x", "y"}, []string{"TT", "T-"})
+	c, err := a.AppendColumns(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole, _ := NewAlignment(nil, []string{"AC-GTT", "ACTGT-"})
+	t.Log(rowStrings(c), c.Score)
+	if strings.Join(rowStrings(c), " ") != "AC-GTT ACTGT-" || c.IDs[1] != "y" {
+		t.Error("Incorrect columns.")
+	}
+	if c.Score != whole.Score {
+		t.Error("Incorrect score.")
+	}
+	if _, err := a.AppendColumns(&Alignment{Rows: a.Rows[:1]}); err == nil {
+		t.Error("expected error for mismatched row counts")
+	}
+	b.IDs = []string{"x", "z"}
+	if _, err := a.AppendColumns(b); err == nil {
+		t.Error("expected error for mismatched ids")
+	}
+	b.IDs = []string{"x"}
+	if _, err := a.AppendColumns(b); err == nil {
+		t.Error("expected error for fewer ids")
+	}
+	b.IDs = nil
+	if _, err := a.AppendColumns(b); err == nil {
+		t.Error("expected error for ids on one side only")
+	}
+	if _, err := b.AppendColumns(a); err == nil {
+		t.Error("expected error for ids on the other side only")
+	}
+}
+
+func TestMoveGap(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"AC-GT", "ACTGT"})
+	if err := a.MoveGap(0, 2, 4); err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := NewAlignment(nil, []string{"ACGT-", "ACTGT"})
+	t.Log(rowStrings(a), a.Score)
+	if rowStrings(a)[0] != "ACGT-" || a.Score != expected.Score {
+		t.Error("Incorrect score.")
+	}
+	if err := a.MoveGap(0, 4, 0); err != nil || rowStrings(a)[0] != "-ACGT" {
+		t.Errorf("got %v (%v)", rowStrings(a)[0], err)
+	}
+	expected, _ = NewAlignment(nil, []string{"-ACGT", "ACTGT"})
+	if a.Score != expected.Score {
+		t.Error("Incorrect score.")
+	}
+	if err := a.MoveGap(1, 0, 2); err == nil {
+		t.Error("expected error for moving a base")
+	}
+
+	cfg := bio.DefaultScoreConfig()
+	cfg.Forbid(bio.C, bio.T)
+	a.Rescore(cfg)
+	if !math.IsInf(a.Score, -1) {
+		t.Errorf("got %v", a.Score)
+	}
+	// -ACGT over ACTGT pairs C with T in column 2 until the gap moves past it
+	a.MoveGap(0, 0, 2)
+	if math.IsInf(a.Score, -1) || a.RescoreColumn(2) != int64(cfg.Gap) {
+		t.Errorf("got %v", a.Score)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	for _, rows := range [][]string{
+		{"AAAT", "A-AT"},
+		{"AAAT", "AA-T"},
+		{"AAAT", "-AAT"},
+	} {
+		a, _ := NewAlignment(nil, rows)
+		score := a.Score
+		a.Exact = true
+		a.Canonicalize()
+		if got := strings.Join(rowStrings(a), " "); got != "AAAT -AAT" {
+			t.Errorf("%v: got %v", rows, got)
+		}
+		if a.Score != score || !a.Exact {
+			t.Error("Incorrect score.")
+		}
+	}
+	// moving the gap would pair C with A, so it stays
+	a, _ := NewAlignment(nil, []string{"ACT", "A-T"})
+	a.Canonicalize()
+	if rowStrings(a)[1] != "A-T" {
+		t.Errorf("got %v", rowStrings(a)[1])
+	}
+}
+
+func TestWorstColumn(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"ACGTA", "ACTTA", "AC-TA"})
+	if i, s := a.WorstColumn(); i != 2 || s != -4+2*(-3) {
+		t.Errorf("got %v %v", i, s)
+	}
+	a.Rescore(bio.EditDistanceConfig())
+	if i, s := a.WorstColumn(); i != 2 || s != -6 {
+		t.Errorf("edit distance: got %v %v", i, s)
+	}
+	if i, _ := (&Alignment{}).WorstColumn(); i != -1 {
+		t.Errorf("empty: got %v", i)
+	}
+}
+
+func TestCenteredColumnScores(t *testing.T) {
+	// half A and half T: random pairs match with probability 1/2
+	a, _ := NewAlignment(nil, []string{"AT", "AT", "TA"})
+	baseline := a.BaselineColumnScore()
+	if baseline != 3*(6-4)/2 {
+		t.Errorf("baseline: got %v", baseline)
+	}
+	if c := a.CenteredColumnScores(baseline); fmt.Sprint(c) != "[-5 -5]" {
+		t.Errorf("got %v", c)
+	}
+	b, _ := NewAlignment(nil, []string{"ACGT", "ACGT"})
+	if c := b.CenteredColumnScores(b.BaselineColumnScore()); c[0] <= 0 {
+		t.Errorf("conserved column centered to %v", c[0])
+	}
+}
+
+func TestWriteA2M(t *testing.T) {
+	var buf bytes.Buffer
+	match := []bool{true, false, true, true}
+	if err := WriteA2M(&buf, []string{"a", "b"}, []string{"ACGT", "a.-t"}, match); err != nil {
+		t.Fatal(err)
+	}
+	if expected := ">a\nAcGT\n>b\nA.-T\n"; buf.String() != expected {
+		t.Errorf("got %q, expected %q", buf.String(), expected)
+	}
+	if err := WriteA2M(&buf, []string{"a"}, []string{"ACGT"}, match[:3]); err == nil {
+		t.Error("expected error for a short match mask")
+	}
+	if err := WriteA2M(&buf, nil, []string{"ACGT"}, match); err == nil {
+		t.Error("expected error for missing ids")
+	}
+}
+
+func TestAlignmentWriter(t *testing.T) {
+	long := strings.Repeat("ACGT", 16)
+	a, _ := NewAlignment([]string{"x", "seq_y"}, []string{long, "-" + long[1:]})
+	var buf bytes.Buffer
+	if err := a.WriteBlocks(NewFASTAWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	expected := ">x\n" + long[:60] + "\nACGT\n>seq_y\n-" + long[1:60] + "\nACGT\n"
+	if buf.String() != expected {
+		t.Errorf("fasta: got\n%v", buf.String())
+	}
+	buf.Reset()
+	a.WriteBlocks(NewPHYLIPWriter(&buf))
+	expected = "2 64\nx         " + long[:60] + "\nseq_y     -" + long[1:60] + "\n\nACGT\nACGT\n"
+	if buf.String() != expected {
+		t.Errorf("phylip: got\n%v", buf.String())
+	}
+	buf.Reset()
+	b, _ := NewAlignment(nil, []string{"ACG", "AC-"})
+	b.WriteBlocks(NewClustalWriter(&buf))
+	expected = "CLUSTAL W multiple sequence alignment\n\n0    ACG\n1    AC-\n     ** \n     994\n\n"
+	if buf.String() != expected {
+		t.Errorf("clustal: got\n%q", buf.String())
+	}
+	// ambiguity codes are written as themselves, not as gaps
+	buf.Reset()
+	c := &Alignment{Rows: []*bio.Sequence{bio.AToIUPAC("ACRY-"), bio.AToIUPAC("ACRTN")}}
+	c.WriteBlocks(NewFASTAWriter(&buf))
+	if expected = ">0\nACRY-\n>1\nACRTN\n"; buf.String() != expected {
+		t.Errorf("fasta: got %q, expected %q", buf.String(), expected)
+	}
+	aw := NewClustalWriter(&buf)
+	if err := aw.WriteBlock([][]bio.Base{{bio.A}}); err == nil {
+		t.Error("expected error for a block before the header")
+	}
+	aw.WriteHeader([]string{"a", "b"}, 2)
+	if err := aw.WriteBlock([][]bio.Base{{bio.A, bio.C, bio.G}, {bio.A, bio.C, bio.G}}); err == nil {
+		t.Error("expected error for too many columns")
+	}
+	if err := NewPHYLIPWriter(&buf).WriteHeader([]string{"a b"}, 1); err == nil {
+		t.Error("expected error for an id with a space")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	a, _ := NewAlignment([]string{"x", "", "z"}, []string{"--ACGTN-TT", "AAAAAAAAAA", "----------"})
+	a.Score, a.Exact = 1.0/3, true
+	var buf bytes.Buffer
+	if err := a.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.Len())
+	b, err := DecodeAlignment(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(rowStrings(b), " ") != strings.Join(rowStrings(a), " ") ||
+		strings.Join(b.IDs, ",") != "x,,z" || b.Score != a.Score || !b.Exact {
+		t.Errorf("round trip gave %v %q %v", rowStrings(b), b.IDs, b.Score)
+	}
+	a.IDs = nil
+	buf.Reset()
+	a.Encode(&buf)
+	if b, _ := DecodeAlignment(&buf); b == nil || b.IDs != nil {
+		t.Error("expected no ids after round trip")
+	}
+	if _, err := DecodeAlignment(strings.NewReader("ALN\x01\x01\x02\x00")); err == nil {
+		t.Error("expected error for truncated input")
+	}
+	// headers claiming far more data than follows: a 2^31-1 byte id and a
+	// run of 2^31-1 packed bases
+	for _, in := range []string{
+		"ALN\x01\x01\x01\x01\xff\xff\xff\xff\x07xy",
+		"ALN\x01\x01\xff\xff\xff\xff\x07\x00\x00\x00\x00\x00\x00\x00\x00\x00\xfc\xff\xff\xff\x1f\x1b",
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := DecodeAlignment(strings.NewReader(in))
+		runtime.ReadMemStats(&after)
+		if err == nil {
+			t.Errorf("%q: expected error for a hostile header", in)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("%q: allocated %v bytes", in, n)
+		}
+	}
+	a.Rows[0].Bases[0] = bio.Base(42)
+	if err := a.Encode(&buf); err == nil {
+		t.Error("expected error for an invalid base")
+	}
+}
+
+func TestScoreAlignment(t *testing.T) {
+	// columns: A/A match, C/- gap, G/G match, T/C mismatch
+	score, err := ScoreAlignment([]string{"ACGT", "A-GC"}, bio.DefaultScoreConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != (6-3+6-4)/2.0 {
+		t.Errorf("Incorrect score %v.", score)
+	}
+	score, _ = ScoreAlignment([]string{"ACGT", "A-GC"}, bio.EditDistanceConfig())
+	if score != -2 {
+		t.Errorf("Incorrect score %v.", score)
+	}
+	if _, err := ScoreAlignment([]string{"ACGT", "A-G"}, bio.DefaultScoreConfig()); err == nil {
+		t.Error("expected error for rows of differing length")
+	}
+	if _, err := ScoreAlignment([]string{"ACGT", "A-GN"}, bio.DefaultScoreConfig()); err == nil {
+		t.Error("expected error for unknown symbol")
+	}
+}
+
+func FuzzScoreAlignment(f *testing.F) {
+	f.Add("ACGT\nA-GC")
+	f.Add("..ACGT.\nAAAC-TT\n-------")
+	f.Add("ACGT\nA-G")
+	f.Add("ACGT\nA-GN")
+	f.Fuzz(func(t *testing.T, in string) {
+		rows := strings.Split(in, "\n")
+		cfg := bio.DefaultScoreConfig()
+		score, err := ScoreAlignment(rows, cfg)
+		if err != nil {
+			return
+		}
+		for i, row := range rows {
+			if len(row) != len(rows[0]) {
+				t.Fatalf("row %v has length %v, expected %v", i, len(row), len(rows[0]))
+			}
+		}
+		// no pair in a column scores more than the largest weight
+		limit := bio.Max(cfg.Match, -cfg.Mismatch, -cfg.Gap)
+		pairs := len(rows) * (len(rows) - 1) / 2
+		if bound := bio.ToNatural(int64(pairs * len(rows[0]) * limit)); math.Abs(score) > bound {
+			t.Errorf("score %v exceeds bound %v", score, bound)
+		}
+	})
+}
+
+func TestGapCounts(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{
+		"--AC-GT---",
+		"A-ACGGTA-T",
+	})
+	if a.GapOpens() != 5 || a.GapExtends() != 3 {
+		t.Errorf("got %v opens and %v extends", a.GapOpens(), a.GapExtends())
+	}
+}
+
+func TestRunScore(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	a, _ := NewAlignment(nil, []string{"AC---GT", "A-GT-GT", "ACG--G-"})
+	linear := func(n int) int { return n * cfg.Gap }
+	if s := a.RunScore(cfg, linear); s != a.Score {
+		t.Errorf("linear: got %v, expected %v", s, a.Score)
+	}
+	// rows 0 and 1 project to AC--GT/A-GTGT: the gaps in row 0 are one run
+	// and the gap in row 1 another
+	pair, _ := NewAlignment(nil, []string{"AC---GT", "A-GT-GT"})
+	affine := bio.AffineGapCost(-10, -1)
+	if s := pair.RunScore(cfg, affine); s != bio.ToNatural(3*6+(-10)+(-10-1)) {
+		t.Errorf("affine: got %v", s)
+	}
+	logCost := bio.LogGapCost(-10, -2)
+	if logCost(1) != -10 || logCost(8) != -10-4 {
+		t.Errorf("log cost: got %v %v", logCost(1), logCost(8))
+	}
+}
+
+// verifyAffineScore recomputes RunScore under AffineGapCost(open, extend)
+// column by column instead of by run: within each projected pair of rows
+// a gap is charged open when that row held a base in the previous projected
+// column and extend when it already held a gap.
+func (a *Alignment) verifyAffineScore(cfg bio.ScoreConfig, open, extend int) float64 {
+	sum := 0
+	for i := range a.Rows {
+		for j := i + 1; j < len(a.Rows); j++ {
+			var wasGap [2]bool
+			for k, bi := range a.Rows[i].Bases {
+				pair := [2]bio.Base{bi, a.Rows[j].Bases[k]}
+				if pair[0] == bio.X && pair[1] == bio.X {
+					continue
+				}
+				for r, b := range pair {
+					switch {
+					case b != bio.X:
+					case wasGap[r]:
+						sum += extend
+					default:
+						sum += open
+					}
+					wasGap[r] = b == bio.X
+				}
+				if pair[0] != bio.X && pair[1] != bio.X {
+					sum += int(cfg.PairScore(pair[0], pair[1]))
+				}
+			}
+		}
+	}
+	return bio.ToNatural(int64(sum))
+}
+
+func TestAffineGapOpen(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	open, extend := -10, -1
+	affine := bio.AffineGapCost(open, extend)
+	// rows 1 and 2 open their gaps in the same column: each is charged one
+	// open against row 0 and nothing against the other
+	a, _ := NewAlignment(nil, []string{"AAAA", "A--A", "A--A"})
+	expected := bio.ToNatural(int64(2*(2*6+open+extend) + 2*6))
+	if s := a.RunScore(cfg, affine); s != expected || a.verifyAffineScore(cfg, open, extend) != expected {
+		t.Errorf("got %v, expected %v", s, expected)
+	}
+	// a gap run passing from one row to the other opens twice
+	b, _ := NewAlignment(nil, []string{"AC--", "--AC"})
+	if s := b.RunScore(cfg, affine); s != bio.ToNatural(int64(2*(open+extend))) {
+		t.Errorf("switching rows: got %v", s)
+	}
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		rows := make([]string, 2+r.Intn(3))
+		for i := range rows {
+			row := make([]byte, 12)
+			for k := range row {
+				row[k] = "ACGT---"[r.Intn(7)]
+			}
+			rows[i] = string(row)
+		}
+		a, _ := NewAlignment(nil, rows)
+		if s, v := a.RunScore(cfg, affine), a.verifyAffineScore(cfg, open, extend); s != v {
+			t.Fatalf("%q: RunScore %v, recomputed %v", rows, s, v)
+		}
+	}
+}
+
+func TestGapFraction(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{
+		"--AC-GT---",
+		"A-ACGGTA-T",
+	})
+	if o := a.Occupancy(); fmt.Sprint(o) != "[1 0 2 2 1 2 2 1 0 1]" {
+		t.Errorf("occupancy: got %v", o)
+	}
+	if f := a.GapFraction(); f != 8.0/20 {
+		t.Errorf("got %v", f)
+	}
+	if f := (&Alignment{}).GapFraction(); f != 0 {
+		t.Errorf("empty alignment: got %v", f)
+	}
+}
+
+func TestPercentIdentity(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{
+		"ACGT--A",
+		"ACCTG-A",
+	})
+	if id := a.PercentIdentity(0, 1, ExcludeGaps); id != 80 {
+		t.Errorf("gap exclusion: got %v", id)
+	}
+	if id := a.PercentIdentity(0, 1, GapsMismatch); math.Abs(id-400.0/6) > 1e-9 {
+		t.Errorf("gaps as mismatches: got %v", id)
+	}
+}
+
+func TestMostDivergent(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{
+		"ACGTACGT",
+		"ACGTACGA",
+		"TTGAACCA",
+		"ACGTACGT",
+	})
+	i, mean := a.MostDivergent()
+	t.Log(i, mean)
+	if i != 2 || math.Abs(mean-(37.5+50+37.5)/3) > 1e-9 {
+		t.Error("Incorrect outlier.")
+	}
+	if i, _ := (&Alignment{Rows: a.Rows[:1]}).MostDivergent(); i != -1 {
+		t.Errorf("single row: got %v", i)
+	}
+}
+
+func TestIdentityToConsensus(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"AC-GT", "AC-GA", "TCNG-"})
+	if c := rowStrings(&Alignment{Rows: []*bio.Sequence{a.Consensus()}}); c[0] != "ACNGA" {
+		t.Errorf("consensus: got %v", c)
+	}
+	// an ambiguity code counts for each of its bases: R, Y and C give C
+	// 1.5 against 0.5 for the others, and R and S outweigh A with G
+	b := &Alignment{Rows: []*bio.Sequence{bio.AToIUPAC("ARAR"), bio.AToIUPAC("GYNS"), bio.AToIUPAC("GCN-")}}
+	if c := rowStrings(&Alignment{Rows: []*bio.Sequence{b.Consensus()}}); c[0] != "GCAG" {
+		t.Errorf("ambiguous consensus: got %v", c)
+	}
+	identities := a.IdentityToConsensus()
+	t.Log(identities)
+	if fmt.Sprint(identities) != "[75 100 75]" {
+		t.Error("Incorrect identities.")
+	}
+}
+
+func TestDiffAgainst(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{
+		"AC-GTA",
+		"ACTGTA",
+		"AG-G-N",
+		"AT-GTA",
+	})
+	variants := a.DiffAgainst(0)
+	t.Log(variants)
+	expected := []Variant{
+		{Row: 1, Column: 2, RefPos: 2, Kind: Insertion, Ref: bio.X, Alt: bio.T},
+		{Row: 2, Column: 1, RefPos: 1, Kind: Substitution, Ref: bio.C, Alt: bio.G},
+		{Row: 2, Column: 4, RefPos: 3, Kind: Deletion, Ref: bio.T, Alt: bio.X},
+		{Row: 3, Column: 1, RefPos: 1, Kind: Substitution, Ref: bio.C, Alt: bio.T},
+	}
+	if fmt.Sprint(variants) != fmt.Sprint(expected) {
+		t.Error("Incorrect variants.")
+	}
+}
+
+func TestDegenerateConsensus(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"AAC-TA-", "GAT-TCN", "AAG-T--"})
+	if c := a.DegenerateConsensus(); c != "RAB-TMN" {
+		t.Errorf("got %v", c)
+	}
+	if c := a.DegenerateConsensusWith(0.5); c != "RAB-TM-" {
+		t.Errorf("half gaps: got %v", c)
+	}
+	b := &Alignment{Rows: []*bio.Sequence{bio.AToIUPAC("ARAR"), bio.AToIUPAC("GYNS"), bio.AToIUPAC("GCN-")}}
+	if c := b.DegenerateConsensus(); c != "RNNV" {
+		t.Errorf("ambiguous: got %v", c)
+	}
+}
+
+func TestCompressedView(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{
+		"ACGTTA-CGGGG",
+		"ACGTCA-CGGGG",
+	})
+	expected := "[4×]TA-[5×]\n[4×]CA-[5×]"
+	if v := a.CompressedView(); v != expected {
+		t.Errorf("got\n%v\nexpected\n%v", v, expected)
+	}
+	// ambiguity codes print as themselves, not as gaps
+	a = &Alignment{Rows: []*bio.Sequence{bio.AToIUPAC("ACRT-"), bio.AToIUPAC("ACGYN")}}
+	expected = "[2×]RT-\n[2×]GYN"
+	if v := a.CompressedView(); v != expected {
+		t.Errorf("got\n%v\nexpected\n%v", v, expected)
+	}
+}
+
+func TestScoreAlignmentGapChars(t *testing.T) {
+	dashes, _ := ScoreAlignment([]string{"--ACGT-", "AAAC-TT"}, bio.DefaultScoreConfig())
+	dots, err := ScoreAlignment([]string{"..ACGT.", "AAAC-TT"}, bio.DefaultScoreConfig())
+	if err != nil || dots != dashes {
+		t.Errorf("'.' should score like '-': %v %v (%v)", dots, dashes, err)
+	}
+	defer func(g string) { bio.GapChars = g }(bio.GapChars)
+	bio.GapChars = "-"
+	if _, err := ScoreAlignment([]string{"..ACGT.", "AAAC-TT"}, bio.DefaultScoreConfig()); err == nil {
+		t.Error("expected '.' to be rejected once removed from GapChars")
+	}
+}
+
+func TestRefine(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	// a deliberately poor, left-justified starting alignment
+	a, _ := NewAlignment([]string{"a", "b", "c"}, []string{
+		"ACGTTGCA--",
+		"AGTTGCA---",
+		"CCACGTTGCA",
+	})
+	r := a.Refine(10, cfg)
+	t.Log(r.Score)
+	if r.Score <= a.Score {
+		t.Errorf("refinement did not improve the score: %v <= %v", r.Score, a.Score)
+	}
+	if s, _ := ScoreAlignment(rowStrings(r), cfg); s != r.Score {
+		t.Errorf("reported score %v, rescored %v", r.Score, s)
+	}
+	for i, row := range []string{"ACGTTGCA", "AGTTGCA", "CCACGTTGCA"} {
+		if ungapped := strings.Replace(rowStrings(r)[i], "-", "", -1); ungapped != row {
+			t.Errorf("row %v changed: %v", i, ungapped)
+		}
+	}
+	if r.IDs[2] != "c" {
+		t.Error("ids not preserved")
+	}
+
+	e, err := RefineExisting(rowStrings(a), 10, cfg)
+	if err != nil || e.Score != r.Score {
+		t.Errorf("existing: got %v, %v", e, err)
+	}
+	if _, err := RefineExisting([]string{"ACGT", "AC-"}, 10, cfg); err == nil {
+		t.Error("expected error for ragged rows")
+	}
+
+	var trajectory []float64
+	f := a.RefineFunc(10, cfg, func(iter int, score float64, b *Alignment) {
+		if iter != len(trajectory) || score != b.Score {
+			t.Errorf("iteration %v: unexpected callback", iter)
+		}
+		trajectory = append(trajectory, score)
+	})
+	t.Log(trajectory)
+	if len(trajectory) == 0 || trajectory[len(trajectory)-1] != f.Score || f.Score != r.Score {
+		t.Error("Incorrect score trajectory.")
+	}
+
+	// for two rows a single realignment is an optimal pairwise alignment
+	p, _ := NewAlignment(nil, []string{"ACGT", "AGT-"})
+	if s := p.Refine(1, cfg).Score; s != 7.5 {
+		t.Errorf("Incorrect score %v.", s)
+	}
+}
+
+func rowStrings(a *Alignment) (rows []string) {
+	for _, row := range a.Rows {
+		s := ""
+		for _, b := range row.Bases {
+			s += string(symbol(b))
+		}
+		rows = append(rows, s)
+	}
+	return
+}
+
+func TestReweightAlign(t *testing.T) {
+	cfg := bio.DefaultScoreConfig()
+	seqStrings := []string{"ACGTTGCA", "AGTTGCA", "CCACGTTGCA", "ACGTGCA"}
+	a, err := ReweightAlign(seqStrings, 0, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(rowStrings(a), a.Score)
+	for i, row := range seqStrings {
+		if ungapped := strings.Replace(rowStrings(a)[i], "-", "", -1); ungapped != row {
+			t.Errorf("row %v changed: %v", i, ungapped)
+		}
+	}
+	if s, _ := ScoreAlignment(rowStrings(a), cfg); s != a.Score {
+		t.Errorf("reported score %v, rescored %v", a.Score, s)
+	}
+	same, _ := ReweightAlign([]string{"ACGT", "ACGT"}, 1, cfg)
+	if strings.Join(rowStrings(same), " ") != "ACGT ACGT" {
+		t.Error("Incorrect alignment.")
+	}
+	if _, err := ReweightAlign(nil, 1, cfg); err == nil {
+		t.Error("expected error for no sequences")
+	}
+}
+
+func TestCoordinateMap(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"-AC--GT", "AACCGGT"})
+	expected := []int{1, 2, 5, 6}
+	cols := a.CoordinateMap(0)
+	if len(cols) != len(expected) {
+		t.Fatalf("got %v", cols)
+	}
+	for i := range cols {
+		if cols[i] != expected[i] {
+			t.Errorf("residue %v: got column %v, expected %v", i, cols[i], expected[i])
+		}
+	}
+}
+
+func TestAnnotatedRows(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"-AC--GT", "AACCGGT"})
+	rows := a.AnnotatedRows()
+	t.Log(rows)
+	if fmt.Sprint(rows[0]) != "[-1 0 1 -1 -1 2 3]" || fmt.Sprint(rows[1]) != "[0 1 2 3 4 5 6]" {
+		t.Error("Incorrect annotation.")
+	}
+	for pos, col := range a.CoordinateMap(0) {
+		if rows[0][col] != pos {
+			t.Errorf("column %v: got %v, expected %v", col, rows[0][col], pos)
+		}
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	a, _ := NewAlignment([]string{"x", "y"}, []string{"-AC--GT", "AACCGGT"})
+	blocks := a.Blocks()
+	t.Log(blocks)
+	if fmt.Sprint(blocks["x"]) != "[[1 3] [5 7]]" || fmt.Sprint(blocks["y"]) != "[[0 7]]" {
+		t.Error("Incorrect blocks.")
+	}
+	a.IDs = nil
+	if _, ok := a.Blocks()["1"]; !ok {
+		t.Error("expected rows keyed by index without ids")
+	}
+}
+
+func TestMutualInformation(t *testing.T) {
+	// columns 0 and 1 covary perfectly, column 2 is conserved and the gaps
+	// of column 3 covary with column 4
+	a, _ := NewAlignment(nil, []string{
+		"AAGAA",
+		"CCGAA",
+		"AAG-C",
+		"CCG-C",
+	})
+	mi := a.MutualInformation()
+	t.Log(mi)
+	if math.Abs(mi[0][1]-1) > 1e-9 || mi[0][2] != 0 || mi[0][0] != 0 || mi[1][0] != mi[0][1] {
+		t.Error("Incorrect mutual information.")
+	}
+	if mi[3][4] != 0 {
+		t.Errorf("gaps excluded: got %v", mi[3][4])
+	}
+	if gapped := a.MutualInformationWith(true, false); math.Abs(gapped[3][4]-1) > 1e-9 {
+		t.Errorf("gaps as symbol: got %v", gapped[3][4])
+	}
+	apc := a.MutualInformationWith(false, true)
+	if apc[0][1] >= mi[0][1] {
+		t.Errorf("apc: got %v", apc[0][1])
+	}
+}
+
+func TestPSSM(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"AC-", "AG-", "AT-"})
+	pssm := a.PSSM()
+	// column 0: p(A) = (3 + 0.25) / 4
+	if math.Abs(pssm[0][bio.A]-math.Log2(3.25)) > 1e-9 {
+		t.Errorf("got %v", pssm[0][bio.A])
+	}
+	if pssm[0][bio.C] >= 0 || pssm[1][bio.A] >= 0 || pssm[1][bio.G] <= 0 {
+		t.Errorf("unexpected signs %v", pssm)
+	}
+	for _, s := range pssm[2] {
+		if s != 0 {
+			t.Errorf("all-gap column should score 0, got %v", pssm[2])
+		}
+	}
+	skewed := a.PSSMWith([]float64{0.7, 0.1, 0.1, 0.1}, 1)
+	if skewed[0][bio.A] >= pssm[0][bio.A] {
+		t.Error("a common background base should score lower")
+	}
+}
+
+func TestTerminalGapMask(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"--A-C-", "----AC"})
+	expected := [][]bool{
+		{true, true, false, false, false, true},
+		{true, true, true, true, false, false},
+	}
+	for i, row := range a.TerminalGapMask() {
+		for j, v := range row {
+			if v != expected[i][j] {
+				t.Errorf("row %v column %v: got %v", i, j, v)
+			}
+		}
+	}
+}
+
+func TestLogExpectation(t *testing.T) {
+	if le := LogExpectation([]bio.Base{bio.A, bio.A, bio.A, bio.A}, nil); math.Abs(le-math.Log(4)) > 1e-9 {
+		t.Errorf("conserved column: got %v", le)
+	}
+	if le := LogExpectation([]bio.Base{bio.A, bio.C, bio.G, bio.T}, nil); math.Abs(le) > 1e-9 {
+		t.Errorf("background column: got %v", le)
+	}
+	if le := LogExpectation([]bio.Base{bio.A, bio.A, bio.X, bio.X}, nil); math.Abs(le-math.Log(4)/2) > 1e-9 {
+		t.Errorf("half gapped column: got %v", le)
+	}
+	if le := LogExpectation([]bio.Base{bio.X}, nil); le != 0 {
+		t.Errorf("gap column: got %v", le)
+	}
+}
+
+func TestStats(t *testing.T) {
+	a, _ := NewAlignment(nil, []string{"ACGTN-", "ACTT-A", "AC-TNA"})
+	s := a.Stats()
+	// A, C and the T column are conserved and the gapped final column agrees
+	// on its bases; N never agrees
+	if s.Columns != 6 || s.Conserved != 3 || s.PercentIdentity != 100*4.0/6 || !slices.Equal(s.Gaps, []int{1, 1, 1}) {
+		t.Errorf("got %+v", s)
+	}
+	if s := (&Alignment{}).Stats(); s.Columns != 0 || s.PercentIdentity != 0 {
+		t.Errorf("empty: got %+v", s)
+	}
+}
+
+func TestGuidedProgressive(t *testing.T) {
+	seqStrings := []string{"ACGTACGT", "TTTTGGGG", "ACGTACG", "TTTGGGG"}
+	// 0 and 2 are close, as are 1 and 3
+	dm := [][]float64{{0, 1, 0.1, 1}, {1, 0, 1, 0.1}, {0.1, 1, 0, 1}, {1, 0.1, 1, 0}}
+	a, err := GuidedProgressive(seqStrings, dm, bio.DefaultScoreConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rowStrings(a) {
+		if strings.ReplaceAll(row, "-", "") != seqStrings[i] {
+			t.Errorf("row %v: got %v", i, row)
+		}
+	}
+	if want, _ := ScoreAlignment(rowStrings(a), bio.DefaultScoreConfig()); a.Score != want {
+		t.Errorf("got %v, want %v", a.Score, want)
+	}
+	if _, err := GuidedProgressive(seqStrings, dm[:2], bio.DefaultScoreConfig()); err == nil {
+		t.Error("expected a short distance matrix to be rejected")
+	}
+}
@@ -0,0 +1,129 @@
+package msa
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TreeNode is a node of a phylogenetic tree. Leaves have a Name and no
+// Children; Length is the branch length to the parent, 0 at the root.
+type TreeNode struct {
+	Name     string
+	Length   float64
+	Children []*TreeNode
+}
+
+// BuildTree builds an unrooted neighbor-joining tree (Saitou and Nei 1987)
+// from the symmetric distance matrix dm. ids names the leaves and may be nil,
+// in which case leaves are named by index. The tree is returned rooted at
+// the node of the final three-way join, so the root has three children
+// (two for two sequences). Negative branch length estimates are set to 0.
+// It returns nil for an empty matrix.
+func BuildTree(dm [][]float64, ids []string) *TreeNode {
+	n := len(dm)
+	if n == 0 {
+		return nil
+	}
+	nodes := make([]*TreeNode, n)
+	dist := make([][]float64, n)
+	for i := range dm {
+		name := strconv.Itoa(i)
+		if ids != nil {
+			name = ids[i]
+		}
+		nodes[i] = &TreeNode{Name: name}
+		dist[i] = append([]float64(nil), dm[i]...)
+	}
+	active := make([]int, n)
+	for i := range active {
+		active[i] = i
+	}
+	for len(active) > 3 {
+		r := float64(len(active))
+		sums := make(map[int]float64, len(active))
+		for _, i := range active {
+			for _, k := range active {
+				sums[i] += dist[i][k]
+			}
+		}
+		bi, bj := -1, -1
+		best := 0.0
+		for x, i := range active {
+			for _, j := range active[x+1:] {
+				if q := (r-2)*dist[i][j] - sums[i] - sums[j]; bi == -1 || q < best {
+					bi, bj, best = i, j, q
+				}
+			}
+		}
+		li := dist[bi][bj]/2 + (sums[bi]-sums[bj])/(2*(r-2))
+		nodes[bi].Length = branchLength(li)
+		nodes[bj].Length = branchLength(dist[bi][bj] - li)
+		joined := &TreeNode{Children: []*TreeNode{nodes[bi], nodes[bj]}}
+		for _, k := range active {
+			if k != bi && k != bj {
+				d := (dist[bi][k] + dist[bj][k] - dist[bi][bj]) / 2
+				dist[bi][k], dist[k][bi] = d, d
+			}
+		}
+		// the joined node takes bi's slot, bj leaves the active set
+		nodes[bi] = joined
+		for x, k := range active {
+			if k == bj {
+				active = append(active[:x], active[x+1:]...)
+				break
+			}
+		}
+	}
+	switch len(active) {
+	case 1:
+		return nodes[active[0]]
+	case 2:
+		i, j := active[0], active[1]
+		nodes[i].Length = branchLength(dist[i][j] / 2)
+		nodes[j].Length = branchLength(dist[i][j] / 2)
+		return &TreeNode{Children: []*TreeNode{nodes[i], nodes[j]}}
+	}
+	i, j, k := active[0], active[1], active[2]
+	nodes[i].Length = branchLength((dist[i][j] + dist[i][k] - dist[j][k]) / 2)
+	nodes[j].Length = branchLength((dist[i][j] + dist[j][k] - dist[i][k]) / 2)
+	nodes[k].Length = branchLength((dist[i][k] + dist[j][k] - dist[i][j]) / 2)
+	return &TreeNode{Children: []*TreeNode{nodes[i], nodes[j], nodes[k]}}
+}
+
+func branchLength(l float64) float64 {
+	if l < 0 {
+		return 0
+	}
+	return l
+}
+
+// Newick serializes the tree rooted at t in Newick format, e.g.
+// "(a:0.1,b:0.2,(c:0.3,d:0.4):0.5);". Names containing Newick punctuation or
+// white space are single-quoted.
+func (t *TreeNode) Newick() string {
+	var b strings.Builder
+	t.writeNewick(&b, true)
+	b.WriteByte(';')
+	return b.String()
+}
+
+func (t *TreeNode) writeNewick(b *strings.Builder, root bool) {
+	if len(t.Children) > 0 {
+		b.WriteByte('(')
+		for i, c := range t.Children {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			c.writeNewick(b, false)
+		}
+		b.WriteByte(')')
+	}
+	if strings.ContainsAny(t.Name, "()[]':;, \t\n") {
+		b.WriteString("'" + strings.ReplaceAll(t.Name, "'", "''") + "'")
+	} else {
+		b.WriteString(t.Name)
+	}
+	if !root {
+		b.WriteString(":" + strconv.FormatFloat(t.Length, 'g', -1, 64))
+	}
+}
@@ -0,0 +1,80 @@
+package msa
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// uniform background frequencies for A, C, G and T
+var uniformBackground = []float64{0.25, 0.25, 0.25, 0.25}
+
+// PSSM returns a position-specific scoring matrix under a uniform background
+// with a total pseudocount of 1. See PSSMWith.
+func (a *Alignment) PSSM() [][]float64 {
+	return a.PSSMWith(uniformBackground, 1)
+}
+
+// PSSMWith returns a position-specific scoring matrix: one row per column,
+// holding the log2-odds score of A, C, G and T (in that order) against
+// background, which gives their background frequencies. For a column with N
+// non-gap bases of which n are base b the estimated frequency is
+//
+//	p = (n + pseudocount*background[b]) / (N + pseudocount)
+//
+// and the score is log2(p / background[b]). A positive pseudocount keeps
+// every score finite, including for all-gap columns, which score 0.
+func (a *Alignment) PSSMWith(background []float64, pseudocount float64) [][]float64 {
+	pssm := make([][]float64, a.Width())
+	for i := range pssm {
+		counts := make([]float64, bio.AlphabetSize)
+		total := 0.0
+		for _, b := range a.Column(i) {
+			if b >= bio.A && b <= bio.T {
+				counts[b]++
+				total++
+			}
+		}
+		pssm[i] = make([]float64, bio.AlphabetSize)
+		for b, n := range counts {
+			p := (n + pseudocount*background[b]) / (total + pseudocount)
+			pssm[i][b] = math.Log2(p / background[b])
+		}
+	}
+	return pssm
+}
+
+// LogExpectation returns the log-expectation score of a column in the style
+// of MUSCLE, comparing how often two residues drawn from the column agree
+// with how often they would agree by chance:
+//
+//	LE = (1 - fgap) * ln(sum over b of f[b]^2 / background[b])
+//
+// f[b] is the frequency of base b among the column's non-gap bases, fgap the
+// fraction of gaps and background the expected frequencies of A, C, G and T.
+// This is MUSCLE's profile formula with an identity substitution model
+// (p[i][j] = background[i] when i == j, else 0). A fully conserved column
+// scores ln(1/background[b]), a column matching the background scores 0, and
+// a column without bases scores 0. A nil background is uniform.
+func LogExpectation(col []bio.Base, background []float64) float64 {
+	if background == nil {
+		background = uniformBackground
+	}
+	counts := make([]float64, bio.AlphabetSize)
+	total := 0.0
+	for _, b := range col {
+		if b >= bio.A && b <= bio.T {
+			counts[b]++
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	sum := 0.0
+	for b, n := range counts {
+		f := n / total
+		sum += f * f / background[b]
+	}
+	return total / float64(len(col)) * math.Log(sum)
+}
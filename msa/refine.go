@@ -0,0 +1,169 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// Refine improves an alignment by leave-one-out realignment. In each
+// iteration every row in turn is removed, stripped of its gaps and aligned
+// back to the profile of the remaining rows by global dynamic programming
+// under cfg; the new alignment is kept only if its sum-of-pairs score is
+// higher. Refinement stops after the given number of iterations or as soon
+// as a full iteration brings no improvement. The returned alignment is
+// scored under cfg; a is not modified.
+func (a *Alignment) Refine(iterations int, cfg bio.ScoreConfig) *Alignment {
+	return a.RefineFunc(iterations, cfg, nil)
+}
+
+// RefineExisting parses an alignment produced elsewhere, given as gapped rows
+// of equal length, and refines it with Refine instead of aligning the
+// sequences from scratch.
+func RefineExisting(aligned []string, iterations int, cfg bio.ScoreConfig) (*Alignment, error) {
+	a, err := NewAlignment(nil, aligned)
+	if err != nil {
+		return nil, err
+	}
+	return a.Refine(iterations, cfg), nil
+}
+
+// RefineFunc is like Refine but calls onIteration, if not nil, after every
+// iteration with the iteration number (from 0) and the best alignment so
+// far and its score, e.g. to log how the score converges. onIteration must
+// not modify the alignment.
+func (a *Alignment) RefineFunc(iterations int, cfg bio.ScoreConfig, onIteration func(iter int, score float64, a *Alignment)) *Alignment {
+	best := a.slice(0, a.Width(), cfg)
+	if len(a.Rows) < 2 {
+		return best
+	}
+	for iter := 0; iter < iterations; iter++ {
+		improved := false
+		for k := range best.Rows {
+			candidate := best.realign(k, cfg, nil)
+			if candidate.Score > best.Score {
+				best, improved = candidate, true
+			}
+		}
+		if onIteration != nil {
+			onIteration(iter, best.Score, best)
+		}
+		if !improved {
+			break
+		}
+	}
+	return best
+}
+
+// realign removes row k, drops the columns that become all gaps and aligns
+// the ungapped row back to what remains. weights, if not nil, holds a weight
+// per column of a, see alignToProfile.
+func (a *Alignment) realign(k int, cfg bio.ScoreConfig, weights []int) *Alignment {
+	var seq []bio.Base
+	for _, b := range a.Rows[k].Bases {
+		if b != bio.X {
+			seq = append(seq, b)
+		}
+	}
+	var profile [][]bio.Base
+	var pw []int
+	for i := 0; i < a.Width(); i++ {
+		col := a.Column(i)
+		rest := append(col[:k:k], col[k+1:]...)
+		if !allGaps(rest) {
+			profile = append(profile, rest)
+			if weights != nil {
+				pw = append(pw, weights[i])
+			}
+		}
+	}
+
+	b := &Alignment{IDs: append([]string(nil), a.IDs...)}
+	for range a.Rows {
+		b.Rows = append(b.Rows, bio.NewSequence())
+	}
+	for _, col := range alignToProfile(seq, profile, pw, len(a.Rows)-1, cfg) {
+		// the realigned base is last in col, move it back to row k
+		last := len(col) - 1
+		for i := range b.Rows {
+			switch {
+			case i < k:
+				b.Rows[i].Bases = append(b.Rows[i].Bases, col[i])
+			case i == k:
+				b.Rows[i].Bases = append(b.Rows[i].Bases, col[last])
+			default:
+				b.Rows[i].Bases = append(b.Rows[i].Bases, col[i-1])
+			}
+		}
+	}
+	b.Score = b.score(cfg)
+	return b
+}
+
+// alignToProfile globally aligns seq to the profile columns, each holding
+// depth bases, and returns the resulting columns with seq's base appended
+// last. Every combined column is scored with bio.ColumnScore, multiplied by
+// weights[j] for profile column j when weights is not nil; columns inserted
+// for seq have weight 1. Ties prefer aligning a base to a profile column,
+// then gapping seq, then inserting a column for seq.
+func alignToProfile(seq []bio.Base, profile [][]bio.Base, weights []int, depth int, cfg bio.ScoreConfig) [][]bio.Base {
+	gaps := make([]bio.Base, depth)
+	for i := range gaps {
+		gaps[i] = bio.X
+	}
+	join := func(col []bio.Base, b bio.Base) []bio.Base {
+		return append(append([]bio.Base(nil), col...), b)
+	}
+	// score of seq's base b (or a gap) against profile column j
+	score := func(j int, b bio.Base) int64 {
+		s := bio.ColumnScore(join(profile[j], b), cfg)
+		if weights == nil || s == bio.Forbidden {
+			return s
+		}
+		return bio.MulScores(s, int64(weights[j]))
+	}
+	f := bio.Slice2D(len(seq)+1, len(profile)+1, int64(0))
+	for i := range f {
+		for j := range f[i] {
+			if i == 0 && j == 0 {
+				continue
+			}
+			best := bio.Forbidden
+			if i > 0 && j > 0 {
+				best = bio.MaxScore(best, bio.AddScores(f[i-1][j-1], score(j-1, seq[i-1])))
+			}
+			if j > 0 {
+				best = bio.MaxScore(best, bio.AddScores(f[i][j-1], score(j-1, bio.X)))
+			}
+			if i > 0 {
+				best = bio.MaxScore(best, bio.AddScores(f[i-1][j], bio.ColumnScore(join(gaps, seq[i-1]), cfg)))
+			}
+			f[i][j] = best
+		}
+	}
+
+	var cols [][]bio.Base // collected back to front
+	for i, j := len(seq), len(profile); i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && f[i][j] == bio.AddScores(f[i-1][j-1], score(j-1, seq[i-1])):
+			cols = append(cols, join(profile[j-1], seq[i-1]))
+			i, j = i-1, j-1
+		case j > 0 && f[i][j] == bio.AddScores(f[i][j-1], score(j-1, bio.X)):
+			cols = append(cols, join(profile[j-1], bio.X))
+			j--
+		default:
+			cols = append(cols, join(gaps, seq[i-1]))
+			i--
+		}
+	}
+	for i, j := 0, len(cols)-1; i < j; i, j = i+1, j-1 {
+		cols[i], cols[j] = cols[j], cols[i]
+	}
+	return cols
+}
+
+// allGaps reports whether every base of col is a gap
+func allGaps(col []bio.Base) bool {
+	for _, b := range col {
+		if b != bio.X {
+			return false
+		}
+	}
+	return true
+}
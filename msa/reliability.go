@@ -0,0 +1,76 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// ReliabilityScores returns a 0-9 reliability value per column in the
+// manner of T-Coffee's TCS. Every pair of rows is aligned independently by
+// global dynamic programming under cfg, and each pair of residues the
+// column places together is checked against those pairwise alignments.
+// With f the fraction of the column's residue pairs that the pairwise
+// alignments also pair, the value is floor(9*f): 9 when every pairwise
+// alignment agrees with the column, 0 when none does or the column holds
+// fewer than two residues.
+func (a *Alignment) ReliabilityScores(cfg bio.ScoreConfig) []int {
+	positions := a.AnnotatedRows()
+	// paired[i][j][p] is the residue of row j that the pairwise alignment of
+	// rows i and j (i < j) puts against residue p of row i, or -1
+	paired := make([][][]int, len(a.Rows))
+	for i := range a.Rows {
+		paired[i] = make([][]int, len(a.Rows))
+		for j := i + 1; j < len(a.Rows); j++ {
+			paired[i][j] = a.pairwiseMatches(i, j, cfg)
+		}
+	}
+	scores := make([]int, a.Width())
+	for c := range scores {
+		agree, total := 0, 0
+		for i := range a.Rows {
+			p := positions[i][c]
+			if p < 0 {
+				continue
+			}
+			for j := i + 1; j < len(a.Rows); j++ {
+				q := positions[j][c]
+				if q < 0 {
+					continue
+				}
+				total++
+				if paired[i][j][p] == q {
+					agree++
+				}
+			}
+		}
+		if total > 0 {
+			scores[c] = 9 * agree / total
+		}
+	}
+	return scores
+}
+
+// pairwiseMatches aligns the residues of rows i and j on their own and
+// returns, for each residue of row i, the residue of row j it is aligned to,
+// or -1 where it faces a gap
+func (a *Alignment) pairwiseMatches(i, j int, cfg bio.ScoreConfig) []int {
+	var seqs []*bio.Sequence
+	for _, k := range []int{i, j} {
+		seq := bio.NewSequence()
+		for _, b := range a.Rows[k].Bases {
+			if b != bio.X {
+				seq.Bases = append(seq.Bases, b)
+			}
+		}
+		seqs = append(seqs, seq)
+	}
+	pair := progressive(seqs, cfg)
+	positions := pair.AnnotatedRows()
+	matches := make([]int, len(seqs[0].Bases))
+	for p := range matches {
+		matches[p] = -1
+	}
+	for c, p := range positions[0] {
+		if p >= 0 {
+			matches[p] = positions[1][c]
+		}
+	}
+	return matches
+}
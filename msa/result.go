@@ -0,0 +1,67 @@
+package msa
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// AlignmentResult is an alignment in a form encoding/json handles directly,
+// e.g. for a frontend. Rows are strings of one-letter codes with '-' for
+// gaps, so they parse back with NewAlignment.
+type AlignmentResult struct {
+	IDs          []string `json:"ids,omitempty"`
+	Rows         []string `json:"rows"`
+	Score        float64  `json:"score"`
+	Exact        bool     `json:"exact"`
+	Conservation []int    `json:"conservation"` // ConservationTrack
+	Gaps         []int    `json:"gaps"`         // number of gaps in each row
+}
+
+// Result returns the alignment as an AlignmentResult
+func (a *Alignment) Result() AlignmentResult {
+	r := AlignmentResult{
+		IDs:          a.IDs,
+		Score:        a.Score,
+		Exact:        a.Exact,
+		Conservation: a.ConservationTrack(),
+		Gaps:         a.Stats().Gaps,
+	}
+	for _, row := range a.Rows {
+		r.Rows = append(r.Rows, row.String())
+	}
+	return r
+}
+
+// resultJSON is AlignmentResult with a score that may be null
+type resultJSON struct {
+	IDs          []string `json:"ids,omitempty"`
+	Rows         []string `json:"rows"`
+	Score        *float64 `json:"score"`
+	Exact        bool     `json:"exact"`
+	Conservation []int    `json:"conservation"`
+	Gaps         []int    `json:"gaps"`
+}
+
+// MarshalJSON encodes r with a score that is not finite, such as that of a
+// Forbidden alignment, as null, since JSON has no infinities
+func (r AlignmentResult) MarshalJSON() ([]byte, error) {
+	j := resultJSON{IDs: r.IDs, Rows: r.Rows, Exact: r.Exact, Conservation: r.Conservation, Gaps: r.Gaps}
+	if !math.IsInf(r.Score, 0) && !math.IsNaN(r.Score) {
+		j.Score = &r.Score
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes r, reading a null score as negative infinity
+func (r *AlignmentResult) UnmarshalJSON(data []byte) error {
+	var j resultJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	score := math.Inf(-1)
+	if j.Score != nil {
+		score = *j.Score
+	}
+	*r = AlignmentResult{IDs: j.IDs, Rows: j.Rows, Score: score, Exact: j.Exact, Conservation: j.Conservation, Gaps: j.Gaps}
+	return nil
+}
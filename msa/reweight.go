@@ -0,0 +1,92 @@
+package msa
+
+import (
+	"fmt"
+	"slices"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// DefaultReweightIterations is the iteration cap ReweightAlign uses when
+// maxIter is not positive.
+const DefaultReweightIterations = 10
+
+// ReweightAlign builds a progressive alignment of the sequences and then
+// alternates two steps, in the manner of EM:
+//
+//   - estimate a reliability weight for every column from the current
+//     alignment, 1 + its ConservationTrack value, so weights range from 1
+//     for gappy or variable columns to 10 for fully conserved ones;
+//   - realign every row in turn to the rest as Refine does, with each
+//     profile column's score multiplied by its weight.
+//
+// It has converged when an iteration leaves the weights unchanged (which
+// includes the alignment not changing) and otherwise stops after maxIter
+// iterations, DefaultReweightIterations if maxIter <= 0. Weights only guide
+// the realignment; the returned Score is the plain sum-of-pairs score under
+// cfg.
+func ReweightAlign(seqStrings []string, maxIter int, cfg bio.ScoreConfig) (*Alignment, error) {
+	if len(seqStrings) == 0 {
+		return nil, fmt.Errorf("msa: no sequences to align")
+	}
+	if maxIter <= 0 {
+		maxIter = DefaultReweightIterations
+	}
+	a := progressive(cfg.Sequences(seqStrings), cfg)
+	for iter := 0; iter < maxIter; iter++ {
+		before := a.reliabilityWeights()
+		for k := range a.Rows {
+			a = a.realign(k, cfg, a.reliabilityWeights())
+		}
+		if slices.Equal(a.reliabilityWeights(), before) {
+			break
+		}
+	}
+	a.Score = a.score(cfg)
+	return a, nil
+}
+
+// Progressive returns the quick heuristic alignment ReweightAlign starts
+// from: each sequence in input order aligned globally to the profile of the
+// ones before it. It is scored under cfg.
+func Progressive(seqStrings []string, cfg bio.ScoreConfig) (*Alignment, error) {
+	if len(seqStrings) == 0 {
+		return nil, fmt.Errorf("msa: no sequences to align")
+	}
+	a := progressive(cfg.Sequences(seqStrings), cfg)
+	a.Score = a.score(cfg)
+	return a, nil
+}
+
+// reliabilityWeights returns the per-column weights used by ReweightAlign
+func (a *Alignment) reliabilityWeights() []int {
+	track := a.ConservationTrack()
+	for i := range track {
+		track[i]++
+	}
+	return track
+}
+
+// progressive aligns each sequence in input order to the alignment of the
+// ones before it.
+func progressive(seqs []*bio.Sequence, cfg bio.ScoreConfig) *Alignment {
+	a := &Alignment{Rows: []*bio.Sequence{{Bases: append([]bio.Base(nil), seqs[0].Bases...)}}}
+	for _, seq := range seqs[1:] {
+		var profile [][]bio.Base
+		a.Columns(func(col []bio.Base) bool {
+			profile = append(profile, col)
+			return true
+		})
+		cols := alignToProfile(seq.Bases, profile, nil, len(a.Rows), cfg)
+		b := &Alignment{}
+		for i := 0; i <= len(a.Rows); i++ {
+			row := bio.NewSequence()
+			for _, col := range cols {
+				row.Bases = append(row.Bases, col[i])
+			}
+			b.Rows = append(b.Rows, row)
+		}
+		a = b
+	}
+	return a
+}
@@ -0,0 +1,53 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// RunScore returns the sum-of-pairs score of the alignment with gaps charged
+// by run length. For every pair of rows the columns where both are gaps are
+// dropped; pairs of bases then score as under cfg and each maximal run of
+// gaps in one row opposite bases in the other scores cost(run length). For
+// a linear cost, cost(n) = n*cfg.Gap, this equals Score under Pairwise with a zero GapGap.
+func (a *Alignment) RunScore(cfg bio.ScoreConfig, cost bio.GapCostFunc) float64 {
+	sum := int64(0)
+	for i := range a.Rows {
+		for j := i + 1; j < len(a.Rows); j++ {
+			sum = bio.AddScores(sum, pairRunScore(a.Rows[i].Bases, a.Rows[j].Bases, cfg, cost))
+		}
+	}
+	return bio.ToNatural(sum)
+}
+
+// pairRunScore scores one projected pair of rows, see RunScore
+func pairRunScore(r1, r2 []bio.Base, cfg bio.ScoreConfig, cost bio.GapCostFunc) (sum int64) {
+	run, inRow := 0, 0 // length of the current gap run and which row holds it
+	flush := func() {
+		if run > 0 {
+			sum = bio.AddScores(sum, int64(cost(run)))
+			run, inRow = 0, 0
+		}
+	}
+	for k := range r1 {
+		b1, b2 := r1[k], r2[k]
+		switch {
+		case b1 == bio.X && b2 == bio.X:
+			// dropped from the projection, a run continues across it
+		case b1 == bio.X || b2 == bio.X:
+			row := 1
+			if b2 == bio.X {
+				row = 2
+			}
+			// each row opens its own runs: a gap passing straight to the
+			// other row ends one run and opens another
+			if run > 0 && row != inRow {
+				flush()
+			}
+			run++
+			inRow = row
+		default:
+			flush()
+			sum = bio.AddScores(sum, cfg.PairScore(b1, b2))
+		}
+	}
+	flush()
+	return
+}
@@ -0,0 +1,75 @@
+package msa
+
+import (
+	"fmt"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// bases accepted in gapped rows passed to ScoreAlignment
+const alignedBases = "ACGT"
+
+// ScoreAlignment returns the sum-of-pairs score of an existing alignment,
+// such as one produced by another tool, under cfg. The rows must all have
+// the same length and contain only A, C, G, T and the characters in
+// bio.GapChars.
+func ScoreAlignment(aligned []string, cfg bio.ScoreConfig) (float64, error) {
+	for i, row := range aligned {
+		if j := strings.IndexFunc(row, func(r rune) bool {
+			return !strings.ContainsRune(alignedBases, r) && !bio.IsGap(r)
+		}); j >= 0 {
+			return 0, fmt.Errorf("msa: row %v: invalid symbol %q at position %v", i, row[j], j)
+		}
+	}
+	a, err := NewAlignment(nil, aligned)
+	if err != nil {
+		return 0, err
+	}
+	return a.score(cfg), nil
+}
+
+// score sums the column scores of the alignment under cfg
+func (a *Alignment) score(cfg bio.ScoreConfig) float64 {
+	sum := int64(0)
+	if cfg.TerminalGapFree {
+		for c := 0; c < a.Width(); c++ {
+			sum = bio.AddScores(sum, a.columnScore(c, cfg))
+		}
+		return bio.ToNatural(sum)
+	}
+	a.SharedColumns(func(col []bio.Base) bool {
+		sum = bio.AddScores(sum, bio.ColumnScore(col, cfg))
+		return true
+	})
+	return bio.ToNatural(sum)
+}
+
+// columnScore returns the score of column c under cfg, leaving out terminal
+// gaps under cfg.TerminalGapFree
+func (a *Alignment) columnScore(c int, cfg bio.ScoreConfig) int64 {
+	if !cfg.TerminalGapFree {
+		return bio.ColumnScore(a.Column(c), cfg)
+	}
+	return bio.TerminalColumnScore(a.Column(c), a.terminalGaps(c), cfg)
+}
+
+// terminalGaps reports, per row, whether column c is a gap before the row's
+// first base or after its last one
+func (a *Alignment) terminalGaps(c int) []bool {
+	terminal := make([]bool, len(a.Rows))
+	for i, row := range a.Rows {
+		if row.Bases[c] != bio.X {
+			continue
+		}
+		before, after := false, false
+		for k, b := range row.Bases {
+			if b != bio.X {
+				before = before || k < c
+				after = after || k > c
+			}
+		}
+		terminal[i] = !before || !after
+	}
+	return terminal
+}
@@ -0,0 +1,40 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// Stats summarizes an alignment, see Alignment.Stats
+type Stats struct {
+	Columns   int // width of the alignment
+	Conserved int // columns whose rows all hold the same known base
+	// percentage (0-100) of columns holding at least one base in which every
+	// base is the same known one, whatever the gaps; 0 for no columns
+	PercentIdentity float64
+	Gaps            []int // number of gaps in each row
+}
+
+// Stats returns the summary statistics of the alignment
+func (a *Alignment) Stats() Stats {
+	s := Stats{Columns: a.Width(), Gaps: make([]int, len(a.Rows))}
+	identical := 0
+	for c := 0; c < s.Columns; c++ {
+		col := a.Column(c)
+		if conserved(col) {
+			s.Conserved++
+		}
+		var bases []bio.Base
+		for i, b := range col {
+			if b == bio.X {
+				s.Gaps[i]++
+			} else {
+				bases = append(bases, b)
+			}
+		}
+		if len(bases) > 0 && conserved(bases) {
+			identical++
+		}
+	}
+	if s.Columns > 0 {
+		s.PercentIdentity = 100 * float64(identical) / float64(s.Columns)
+	}
+	return s
+}
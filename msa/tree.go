@@ -0,0 +1,94 @@
+// Package msa holds helpers for working with multiple sequence alignments.
+package msa
+
+// upgmaNode is a node of a rooted UPGMA tree. Leaves have no children and
+// height 0; the length of the branch above a node is parent.height - height.
+type upgmaNode struct {
+	leaf        int // sequence index, -1 for internal nodes
+	height      float64
+	left, right *upgmaNode
+	size        int // number of leaves below the node
+}
+
+// upgma builds a rooted tree from a symmetric distance matrix by repeatedly
+// joining the two closest clusters.
+func upgma(dm [][]float64) *upgmaNode {
+	n := len(dm)
+	clusters := make([]*upgmaNode, n)
+	dist := make([][]float64, n)
+	for i := range dm {
+		clusters[i] = &upgmaNode{leaf: i, size: 1}
+		dist[i] = append([]float64(nil), dm[i]...)
+	}
+	for active := n; active > 1; active-- {
+		bi, bj := -1, -1
+		for i := range clusters {
+			if clusters[i] == nil {
+				continue
+			}
+			for j := i + 1; j < n; j++ {
+				if clusters[j] == nil {
+					continue
+				}
+				if bi == -1 || dist[i][j] < dist[bi][bj] {
+					bi, bj = i, j
+				}
+			}
+		}
+		a, b := clusters[bi], clusters[bj]
+		joined := &upgmaNode{leaf: -1, height: dist[bi][bj] / 2, left: a, right: b, size: a.size + b.size}
+		for k := range clusters {
+			if clusters[k] == nil || k == bi || k == bj {
+				continue
+			}
+			d := (dist[bi][k]*float64(a.size) + dist[bj][k]*float64(b.size)) / float64(joined.size)
+			dist[bi][k], dist[k][bi] = d, d
+		}
+		clusters[bi], clusters[bj] = joined, nil
+	}
+	for _, c := range clusters {
+		if c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// TreeWeights returns per-sequence weights derived from the distance matrix
+// dm using the ClustalW scheme (Thompson et al. 1994): a UPGMA tree is built
+// and each sequence receives, for every branch between it and the root, the
+// branch length divided by the number of sequences sharing that branch.
+// Sequences in tight clusters therefore get small weights.
+// The weights are scaled to sum to len(dm), so unweighted input corresponds to
+// every weight being 1. If all distances are zero the weights are all 1.
+func TreeWeights(dm [][]float64) []float64 {
+	weights := make([]float64, len(dm))
+	if len(dm) == 0 {
+		return weights
+	}
+	var walk func(node *upgmaNode, parentHeight, acc float64)
+	walk = func(node *upgmaNode, parentHeight, acc float64) {
+		acc += (parentHeight - node.height) / float64(node.size)
+		if node.leaf >= 0 {
+			weights[node.leaf] = acc
+			return
+		}
+		walk(node.left, node.height, acc)
+		walk(node.right, node.height, acc)
+	}
+	root := upgma(dm)
+	walk(root, root.height, 0)
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	for i := range weights {
+		if total == 0 {
+			weights[i] = 1
+		} else {
+			weights[i] *= float64(len(dm)) / total
+		}
+	}
+	return weights
+}
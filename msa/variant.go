@@ -0,0 +1,66 @@
+package msa
+
+import bio "github.com/bsjcho/bioinf"
+
+// VariantKind says how a row differs from the reference in a column
+type VariantKind int
+
+// Substitution ... enum represents a variant kind
+const (
+	// Substitution is a different base aligned to a reference base.
+	Substitution VariantKind = iota
+	// Insertion is a base aligned to a gap in the reference.
+	Insertion
+	// Deletion is a gap aligned to a reference base.
+	Deletion
+)
+
+// Variant is one column where a row differs from the reference row
+type Variant struct {
+	Row    int // index of the differing row
+	Column int // alignment column
+	// RefPos is the 0-based position of the reference residue in the column
+	// in the ungapped reference. an insertion has no reference residue and
+	// lies just before position RefPos.
+	RefPos int
+	Kind   VariantKind
+	Ref    bio.Base // reference base, bio.X for an insertion
+	Alt    bio.Base // row base, bio.X for a deletion
+}
+
+// DiffAgainst lists, ordered by row and then column, every column where a
+// row other than refIndex differs from row refIndex. Each variant covers a
+// single column, so an indel spanning several columns gives one variant per
+// column. A multi-allelic column, where rows carry different alternates, is
+// reported once per differing row; group by Column to collect its alleles.
+// Columns where either base is N are skipped, since they can't be called.
+func (a *Alignment) DiffAgainst(refIndex int) []Variant {
+	ref := a.Rows[refIndex].Bases
+	var variants []Variant
+	for i, row := range a.Rows {
+		if i == refIndex {
+			continue
+		}
+		refPos := 0
+		for col, b := range row.Bases {
+			r := ref[col]
+			v := Variant{Row: i, Column: col, RefPos: refPos, Ref: r, Alt: b}
+			switch {
+			case r == b || r == bio.N || b == bio.N:
+			case r == bio.X:
+				v.Kind = Insertion
+				variants = append(variants, v)
+			case b == bio.X:
+				v.Kind = Deletion
+				variants = append(variants, v)
+			default:
+				v.Kind = Substitution
+				variants = append(variants, v)
+			}
+			if r != bio.X {
+				refPos++
+			}
+		}
+	}
+	return variants
+}
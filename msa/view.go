@@ -0,0 +1,58 @@
+package msa
+
+import (
+	"fmt"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// CompressedView renders the alignment one row per line with every run of
+// two or more fully conserved columns (all rows holding the same base)
+// replaced by "[N×]", where N is the run length. Variable columns, and
+// conserved columns standing alone, are shown verbatim. The same token is
+// written on every row so the columns stay lined up.
+func (a *Alignment) CompressedView() string {
+	rows := make([]strings.Builder, len(a.Rows))
+	for start := 0; start < a.Width(); {
+		end := start
+		for end < a.Width() && conserved(a.Column(end)) {
+			end++
+		}
+		if end-start >= 2 {
+			for i := range rows {
+				fmt.Fprintf(&rows[i], "[%d×]", end-start)
+			}
+			start = end
+			continue
+		}
+		for i, b := range a.Column(start) {
+			rows[i].WriteByte(symbol(b))
+		}
+		start++
+	}
+	lines := make([]string, len(rows))
+	for i := range rows {
+		lines[i] = rows[i].String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// conserved reports whether every base of col is the same known base
+func conserved(col []bio.Base) bool {
+	for _, b := range col {
+		if b == bio.X || b == bio.N || b != col[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// symbol returns the character used to print b: its one-letter code, with
+// '-' for a gap. A value with no code, which no parser produces, prints as '?'.
+func symbol(b bio.Base) byte {
+	if s := b.String(); len(s) == 1 {
+		return s[0]
+	}
+	return '?'
+}
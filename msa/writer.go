@@ -0,0 +1,265 @@
+package msa
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// BlockWidth is the number of columns per block written by WriteBlocks
+const BlockWidth = 60
+
+// AlignmentWriter writes an alignment in some text format as a stream of
+// column blocks, so the formatted output never has to be held in memory.
+// WriteHeader is called once with the row IDs and the alignment width, then
+// WriteBlock with consecutive runs of columns (block[i] holding row i's
+// bases, all the same length) until width columns were written, and finally
+// Close, which writes anything still pending. Close does not close the
+// underlying io.Writer.
+type AlignmentWriter interface {
+	WriteHeader(ids []string, width int) error
+	WriteBlock(block [][]bio.Base) error
+	Close() error
+}
+
+// WriteBlocks streams the alignment to aw in blocks of BlockWidth columns and
+// closes aw. rows are named by their index ("0", "1", ...) when the alignment
+// has no IDs.
+func (a *Alignment) WriteBlocks(aw AlignmentWriter) error {
+	ids := a.IDs
+	if ids == nil {
+		for i := range a.Rows {
+			ids = append(ids, strconv.Itoa(i))
+		}
+	}
+	if err := aw.WriteHeader(ids, a.Width()); err != nil {
+		return err
+	}
+	for start := 0; start < a.Width(); start += BlockWidth {
+		end := start + BlockWidth
+		if end > a.Width() {
+			end = a.Width()
+		}
+		block := make([][]bio.Base, len(a.Rows))
+		for i, row := range a.Rows {
+			block[i] = row.Bases[start:end]
+		}
+		if err := aw.WriteBlock(block); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+// blockWriter holds what the format writers share: the buffered output and
+// the header, and checks that blocks fit it
+type blockWriter struct {
+	w       *bufio.Writer
+	ids     []string
+	width   int
+	written int // columns written so far
+}
+
+func (bw *blockWriter) header(ids []string, width int) error {
+	if bw.ids != nil {
+		return fmt.Errorf("msa: header written twice")
+	}
+	bw.ids, bw.width = append([]string{}, ids...), width
+	return nil
+}
+
+// check validates block against the header and counts its columns
+func (bw *blockWriter) check(block [][]bio.Base) error {
+	if bw.ids == nil {
+		return fmt.Errorf("msa: block written before header")
+	}
+	if len(block) != len(bw.ids) {
+		return fmt.Errorf("msa: block has %v rows, expected %v", len(block), len(bw.ids))
+	}
+	for i, row := range block {
+		if len(row) != len(block[0]) {
+			return fmt.Errorf("msa: block row %v has length %v, expected %v", i, len(row), len(block[0]))
+		}
+	}
+	if len(block) > 0 {
+		bw.written += len(block[0])
+	}
+	if bw.written > bw.width {
+		return fmt.Errorf("msa: %v columns written, expected %v", bw.written, bw.width)
+	}
+	return nil
+}
+
+// close checks that all columns were written and flushes the output
+func (bw *blockWriter) close() error {
+	if bw.written != bw.width {
+		return fmt.Errorf("msa: %v columns written, expected %v", bw.written, bw.width)
+	}
+	return bw.w.Flush()
+}
+
+// rowString formats gapped bases with '-' for gaps
+func rowString(bases []bio.Base) string {
+	b := make([]byte, len(bases))
+	for i, base := range bases {
+		b[i] = symbol(base)
+	}
+	return string(b)
+}
+
+// longest returns the length of the longest of ids
+func longest(ids []string) (n int) {
+	for _, id := range ids {
+		if len(id) > n {
+			n = len(id)
+		}
+	}
+	return
+}
+
+type fastaWriter struct {
+	blockWriter
+	rows [][]bio.Base
+}
+
+// NewFASTAWriter returns an AlignmentWriter for gapped FASTA, with rows
+// wrapped at BlockWidth. FASTA lists each row in full before the next, so
+// the bases (not their formatted text) are kept until Close.
+func NewFASTAWriter(w io.Writer) AlignmentWriter {
+	return &fastaWriter{blockWriter: blockWriter{w: bufio.NewWriter(w)}}
+}
+
+func (f *fastaWriter) WriteHeader(ids []string, width int) error {
+	if err := f.header(ids, width); err != nil {
+		return err
+	}
+	f.rows = make([][]bio.Base, len(ids))
+	return nil
+}
+
+func (f *fastaWriter) WriteBlock(block [][]bio.Base) error {
+	if err := f.check(block); err != nil {
+		return err
+	}
+	for i, row := range block {
+		f.rows[i] = append(f.rows[i], row...)
+	}
+	return nil
+}
+
+func (f *fastaWriter) Close() error {
+	for i, row := range f.rows {
+		fmt.Fprintf(f.w, ">%v\n", f.ids[i])
+		for start := 0; start < len(row); start += BlockWidth {
+			end := start + BlockWidth
+			if end > len(row) {
+				end = len(row)
+			}
+			fmt.Fprintln(f.w, rowString(row[start:end]))
+		}
+	}
+	return f.close()
+}
+
+type clustalWriter struct {
+	blockWriter
+}
+
+// NewClustalWriter returns an AlignmentWriter for the CLUSTAL format: each
+// block lists every row under its ID followed by a line marking the fully
+// conserved columns with '*' and a line of their ConservationTrack digits.
+func NewClustalWriter(w io.Writer) AlignmentWriter {
+	return &clustalWriter{blockWriter{w: bufio.NewWriter(w)}}
+}
+
+func (c *clustalWriter) WriteHeader(ids []string, width int) error {
+	if err := c.header(ids, width); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(c.w, "CLUSTAL W multiple sequence alignment\n\n")
+	return err
+}
+
+func (c *clustalWriter) WriteBlock(block [][]bio.Base) error {
+	if err := c.check(block); err != nil {
+		return err
+	}
+	pad := longest(c.ids) + 4
+	for i, row := range block {
+		fmt.Fprintf(c.w, "%-*v%v\n", pad, c.ids[i], rowString(row))
+	}
+	marks := []byte(strings.Repeat(" ", len(block[0])))
+	track := make([]int, len(block[0]))
+	col := make([]bio.Base, len(block))
+	for k := range marks {
+		for i, row := range block {
+			col[i] = row[k]
+		}
+		if conserved(col) {
+			marks[k] = '*'
+		}
+		track[k] = conservation(col)
+	}
+	indent := strings.Repeat(" ", pad)
+	_, err := fmt.Fprintf(c.w, "%v%s\n%v%v\n\n", indent, marks, indent, TrackLine(track))
+	return err
+}
+
+func (c *clustalWriter) Close() error {
+	return c.close()
+}
+
+type phylipWriter struct {
+	blockWriter
+	named bool // whether the first block, which carries the names, is out
+}
+
+// NewPHYLIPWriter returns an AlignmentWriter for interleaved relaxed PHYLIP:
+// a line with the number of rows and columns, then the blocks separated by
+// blank lines, the first one with each row preceded by its ID. IDs are
+// padded to at least ten characters and may not contain spaces.
+func NewPHYLIPWriter(w io.Writer) AlignmentWriter {
+	return &phylipWriter{blockWriter: blockWriter{w: bufio.NewWriter(w)}}
+}
+
+func (p *phylipWriter) WriteHeader(ids []string, width int) error {
+	for _, id := range ids {
+		if strings.ContainsAny(id, " \t") {
+			return fmt.Errorf("msa: PHYLIP id %q contains whitespace", id)
+		}
+	}
+	if err := p.header(ids, width); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(p.w, "%v %v\n", len(ids), width)
+	return err
+}
+
+func (p *phylipWriter) WriteBlock(block [][]bio.Base) error {
+	if err := p.check(block); err != nil {
+		return err
+	}
+	if p.named {
+		fmt.Fprintln(p.w)
+	}
+	pad := longest(p.ids) + 1
+	if pad < 10 {
+		pad = 10
+	}
+	for i, row := range block {
+		if !p.named {
+			fmt.Fprintf(p.w, "%-*v", pad, p.ids[i])
+		}
+		fmt.Fprintln(p.w, rowString(row))
+	}
+	p.named = true
+	return nil
+}
+
+func (p *phylipWriter) Close() error {
+	return p.close()
+}
@@ -0,0 +1,108 @@
+package bioinf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	presetsMu sync.RWMutex
+	presets   = map[string]ScoreConfig{
+		"blosum62":      BLOSUM62Config(),
+		"default":       DefaultScoreConfig(),
+		"edit-distance": EditDistanceConfig(),
+	}
+)
+
+// BLOSUM62Config returns the protein scheme of the BLOSUM62 matrix: input is
+// read as amino acids, every pair of residues scores as in BLOSUM62, see
+// BLOSUM62, and a gap costs 4 per residue, doubled like every score
+func BLOSUM62Config() ScoreConfig {
+	return ScoreConfig{Gap: -8, Alphabet: Protein, matrix: blosum62Matrix()}
+}
+
+// BLOSUM62 returns the score of a pair of residues under the BLOSUM62
+// matrix, read from the NCBI table as ReadMatrix reads it
+func BLOSUM62() func(a, b Base) int {
+	return blosum62Matrix().score
+}
+
+// blosum62Matrix parses the table once, so every BLOSUM62Config shares the
+// matrix and compares equal
+var blosum62Matrix = sync.OnceValue(func() *pairMatrix {
+	score, err := ReadMatrix(strings.NewReader(blosum62))
+	if err != nil {
+		panic(err)
+	}
+	return &pairMatrix{score: score}
+})
+
+// blosum62 is the NCBI BLOSUM62 table
+const blosum62 = `#  Matrix made by matblas from blosum62.iij
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  4 -1 -2 -2  0 -1 -1  0 -2 -1 -1 -1 -1 -2 -1  1  0 -3 -2  0 -2 -1  0 -4
+R -1  5  0 -2 -3  1  0 -2  0 -3 -2  2 -1 -3 -2 -1 -1 -3 -2 -3 -1  0 -1 -4
+N -2  0  6  1 -3  0  0  0  1 -3 -3  0 -2 -3 -2  1  0 -4 -2 -3  3  0 -1 -4
+D -2 -2  1  6 -3  0  2 -1 -1 -3 -4 -1 -3 -3 -1  0 -1 -4 -3 -3  4  1 -1 -4
+C  0 -3 -3 -3  9 -3 -4 -3 -3 -1 -1 -3 -1 -2 -3 -1 -1 -2 -2 -1 -3 -3 -2 -4
+Q -1  1  0  0 -3  5  2 -2  0 -3 -2  1  0 -3 -1  0 -1 -2 -1 -2  0  3 -1 -4
+E -1  0  0  2 -4  2  5 -2  0 -3 -3  1 -2 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+G  0 -2  0 -1 -3 -2 -2  6 -2 -4 -4 -2 -3 -3 -2  0 -2 -2 -3 -3 -1 -2 -1 -4
+H -2  0  1 -1 -3  0  0 -2  8 -3 -3 -1 -2 -1 -2 -1 -2 -2  2 -3  0  0 -1 -4
+I -1 -3 -3 -3 -1 -3 -3 -4 -3  4  2 -3  1  0 -3 -2 -1 -3 -1  3 -3 -3 -1 -4
+L -1 -2 -3 -4 -1 -2 -3 -4 -3  2  4 -2  2  0 -3 -2 -1 -2 -1  1 -4 -3 -1 -4
+K -1  2  0 -1 -3  1  1 -2 -1 -3 -2  5 -1 -3 -1  0 -1 -3 -2 -2  0  1 -1 -4
+M -1 -1 -2 -3 -1  0 -2 -3 -2  1  2 -1  5  0 -2 -1 -1 -1 -1  1 -3 -1 -1 -4
+F -2 -3 -3 -3 -2 -3 -3 -3 -1  0  0 -3  0  6 -4 -2 -2  1  3 -1 -3 -3 -1 -4
+P -1 -2 -2 -1 -3 -1 -1 -2 -2 -3 -3 -1 -2 -4  7 -1 -1 -4 -3 -2 -2 -1 -2 -4
+S  1 -1  1  0 -1  0  0  0 -1 -2 -2  0 -1 -2 -1  4  1 -3 -2 -2  0  0  0 -4
+T  0 -1  0 -1 -1 -1 -1 -2 -2 -1 -1 -1 -1 -2 -1  1  5 -2 -2  0 -1 -1  0 -4
+W -3 -3 -4 -4 -2 -2 -3 -2 -2 -3 -2 -3 -1  1 -4 -3 -2 11  2 -3 -4 -3 -2 -4
+Y -2 -2 -2 -3 -2 -1 -2 -3  2 -1 -1 -2 -1  3 -3 -2 -2  2  7 -1 -3 -2 -1 -4
+V  0 -3 -3 -3 -1 -2 -2 -3 -3  3  1 -2  1 -1 -2 -2  0 -3 -1  4 -3 -2 -1 -4
+B -2 -1  3  4 -3  0  1 -1  0 -3 -4  0 -3 -3 -2  0 -1 -4 -3 -3  4  1 -1 -4
+Z -1  0  0  1 -3  3  4 -2  0 -3 -3  1 -1 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+X  0 -1 -1 -1 -2 -1 -1 -1 -1 -1 -1 -1 -1 -1 -2  0  0 -2 -1 -1 -1 -1 -1 -4
+* -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4  1
+`
+
+// RegisterPreset makes cfg available under name, replacing any preset of
+// that name. The built-in presets are "default" (DefaultScoreConfig),
+// "edit-distance" (EditDistanceConfig) and "blosum62" (BLOSUM62Config).
+func RegisterPreset(name string, cfg ScoreConfig) {
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	presets[name] = cfg
+}
+
+// Preset returns the preset registered under name
+func Preset(name string) (ScoreConfig, bool) {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	cfg, ok := presets[name]
+	return cfg, ok
+}
+
+// LookupPreset is like Preset but returns an error naming the known presets
+// if name is not registered, for use with flags and request fields.
+func LookupPreset(name string) (ScoreConfig, error) {
+	if cfg, ok := Preset(name); ok {
+		return cfg, nil
+	}
+	return ScoreConfig{}, fmt.Errorf("bioinf: unknown preset %q, known presets are %v",
+		name, strings.Join(PresetNames(), ", "))
+}
+
+// PresetNames returns the names of all registered presets in sorted order
+func PresetNames() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,79 @@
+package bioinf
+
+import (
+	"fmt"
+	"math"
+	"unicode"
+)
+
+// PropertyScorer scores pairs of amino acid residues by how similar their
+// physicochemical properties are rather than by identity. Each residue maps
+// to a vector of property values and a pair scores the negated Euclidean
+// distance between its vectors, so identical properties score 0 and every
+// other pair is negative. Matrix turns it into a pair score the solvers take.
+type PropertyScorer struct {
+	Properties map[rune][]float64 // keyed by one-letter upper case code
+}
+
+// kyte-doolittle hydropathy index
+var hydropathy = map[rune]float64{
+	'A': 1.8, 'R': -4.5, 'N': -3.5, 'D': -3.5, 'C': 2.5,
+	'Q': -3.5, 'E': -3.5, 'G': -0.4, 'H': -3.2, 'I': 4.5,
+	'L': 3.8, 'K': -3.9, 'M': 1.9, 'F': 2.8, 'P': -1.6,
+	'S': -0.8, 'T': -0.7, 'W': -0.9, 'Y': -1.3, 'V': 4.2,
+}
+
+// KyteDoolittle returns a PropertyScorer over the Kyte-Doolittle hydropathy
+// index of the twenty standard amino acids.
+func KyteDoolittle() PropertyScorer {
+	p := PropertyScorer{Properties: map[rune][]float64{}}
+	for r, h := range hydropathy {
+		p.Properties[r] = []float64{h}
+	}
+	return p
+}
+
+// PairScore returns the property score of residues r1 and r2, which are
+// looked up case-insensitively. it is an error for either to be missing
+// from Properties or for their vectors to differ in length.
+func (p PropertyScorer) PairScore(r1, r2 rune) (float64, error) {
+	v1, ok1 := p.Properties[unicode.ToUpper(r1)]
+	v2, ok2 := p.Properties[unicode.ToUpper(r2)]
+	switch {
+	case !ok1:
+		return 0, fmt.Errorf("bioinf: no properties for residue %q", r1)
+	case !ok2:
+		return 0, fmt.Errorf("bioinf: no properties for residue %q", r2)
+	case len(v1) != len(v2):
+		return 0, fmt.Errorf("bioinf: residues %q and %q have %v and %v properties", r1, r2, len(v1), len(v2))
+	}
+	sum := 0.0
+	for i := range v1 {
+		d := v1[i] - v2[i]
+		sum += d * d
+	}
+	return -math.Sqrt(sum), nil
+}
+
+// Matrix returns the property scores as a pair score over the Protein
+// alphabet, for SolveWithMatrix or ScoreConfig.SetMatrix: each PairScore is
+// multiplied by scale, doubled like every other score and rounded to an int.
+// a pair involving N, a nucleotide or a residue without properties scores 0,
+// as a pair missing from a ReadMatrix matrix does.
+func (p PropertyScorer) Matrix(scale float64) func(a, b Base) int {
+	var scores [len(AminoAcids)][len(AminoAcids)]int
+	for i, r1 := range AminoAcids {
+		for j, r2 := range AminoAcids {
+			if s, err := p.PairScore(r1, r2); err == nil {
+				scores[i][j] = int(math.Round(2 * scale * s))
+			}
+		}
+	}
+	return func(a, b Base) int {
+		i, j := int(a-firstResidue), int(b-firstResidue)
+		if i < 0 || i >= len(AminoAcids) || j < 0 || j >= len(AminoAcids) {
+			return 0
+		}
+		return scores[i][j]
+	}
+}
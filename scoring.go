@@ -1,47 +1,427 @@
 package bioinf
 
+import (
+	"fmt"
+	"math"
+)
+
 var (
 	// values doubled to be able to use integers during calculations
 	// final result is converted to float then divided by two
+	// see ToNatural and ToDoubled
 	match    = 6
 	mismatch = -4
 	gap      = -3
 )
 
+// ToNatural converts a doubled integer score to the natural scale.
+// this is the only place the doubling is undone.
+// a Forbidden score converts to negative infinity.
+func ToNatural(doubled int64) float64 {
+	if doubled == Forbidden {
+		return math.Inf(-1)
+	}
+	return float64(doubled) / 2
+}
+
+// ToDoubled converts a natural-scale score to the doubled integer scale.
+// it returns an error if natural is not a multiple of one half, since such a
+// score has no exact doubled representation.
+func ToDoubled(natural float64) (int, error) {
+	d := natural * 2
+	if d != math.Trunc(d) || math.IsInf(d, 0) || math.Abs(d) > math.MaxInt32 {
+		return 0, fmt.Errorf("bioinf: score %v is not representable as a doubled integer", natural)
+	}
+	return int(d), nil
+}
+
+// ScoreConfig is a sum-of-pairs scoring scheme. Like the package defaults,
+// values are doubled so half-point scores can be expressed as integers.
+// The values are ints, but every score computed from them is an int64, so
+// sums over many pairs and columns have the same range on every GOARCH.
+// A gap aligned to a gap scores GapGap, 0 by default, and the zero Unknown
+// makes an unknown residue N neutral. An ambiguous base, see IUPACBase, scores Match
+// against any base it may be and Mismatch against the others. A matrix set
+// with SetMatrix scores every pair of bases instead, as in BLOSUM62Config.
+type ScoreConfig struct {
+	Match    int
+	Mismatch int
+	Gap      int
+	Unknown  int // N against any base or N; N against a gap scores Gap
+	GapModel GapModel
+
+	ColumnGap int // charged once per gapped column under PerColumn
+
+	// GapGap scores each pair of gaps in a column under Pairwise, e.g. a
+	// small penalty to discourage regions gapped in several sequences at
+	// once. the solvers never place a column of only gaps, whatever its
+	// value.
+	GapGap int
+
+	// TerminalGapFree leaves gaps before a sequence's first base or after
+	// its last one out of the score, see TerminalColumnScore
+	TerminalGapFree bool
+
+	forbidden [AlphabetSize][AlphabetSize]bool // base pairs that may not share a column, see Forbid
+
+	// per-base match scores replacing Match where set, see SetMatch
+	baseMatch    [AlphabetSize]int
+	hasBaseMatch [AlphabetSize]bool
+
+	// Alphabet selects how the solvers read their input strings, see
+	// Sequences. the zero value reads nucleotides.
+	Alphabet Alphabet
+
+	matrix *pairMatrix // scores pairs of bases in place of Match and Mismatch, see SetMatrix
+}
+
+// pairMatrix holds a substitution matrix behind a pointer, so ScoreConfig
+// stays comparable
+type pairMatrix struct {
+	score func(a, b Base) int
+}
+
+// Forbidden is the score of a pair or column that must never be aligned. it
+// is the smallest int64 so it loses every comparison; use AddScores rather
+// than + on scores that may be Forbidden so sums don't wrap around.
+const Forbidden int64 = math.MinInt64
+
+// AddScores returns a + b, or Forbidden if either of them is Forbidden. the
+// sum is taken in int64 whatever the GOARCH, and one beyond its range
+// saturates at math.MaxInt64, or at Forbidden+1 below, rather than wrapping
+// around, so it still compares in the right order and never turns into
+// Forbidden.
+func AddScores(a, b int64) int64 {
+	if a == Forbidden || b == Forbidden {
+		return Forbidden
+	}
+	switch {
+	case b > 0 && a > math.MaxInt64-b:
+		return math.MaxInt64
+	case b < 0 && a < Forbidden+1-b:
+		return Forbidden + 1
+	}
+	return a + b
+}
+
+// MulScores returns n * s in int64, saturated like AddScores, e.g. the score
+// of n pairs scoring s each. it is Forbidden if s is and n is not 0.
+func MulScores(n, s int64) int64 {
+	if n == 0 || s == 0 {
+		return 0
+	}
+	if s == Forbidden {
+		return Forbidden
+	}
+	p := n * s
+	if p/n != s || p/s != n || p == Forbidden {
+		if (n > 0) == (s > 0) {
+			return math.MaxInt64
+		}
+		return Forbidden + 1
+	}
+	return p
+}
+
+// SetMatch sets the score of b aligned to itself, overriding Match for that
+// base, e.g. to reward A and T matches above G and C ones in a GC-rich
+// genome. Mismatches still score Mismatch whatever the bases, so a per-base
+// match below Mismatch would make aligning b to another base pay more than
+// aligning it to itself. b must be one of A, C, G and T.
+func (c *ScoreConfig) SetMatch(b Base, score int) {
+	if b >= A && b <= T {
+		c.baseMatch[b] = score
+		c.hasBaseMatch[b] = true
+	}
+}
+
+// SetMatrix scores every pair of non-gap bases with score, for example a
+// substitution matrix read by ReadMatrix, in place of Match, Mismatch,
+// Unknown and SetMatch. gaps still score Gap and GapGap. configs compare
+// equal only if they share the matrix of one SetMatrix call or preset.
+func (c *ScoreConfig) SetMatrix(score func(a, b Base) int) {
+	c.matrix = &pairMatrix{score: score}
+}
+
+// Matrix returns the pair score set by SetMatrix, or nil
+func (c ScoreConfig) Matrix() func(a, b Base) int {
+	if c.matrix == nil {
+		return nil
+	}
+	return c.matrix.score
+}
+
+// Sequences reads seqStrs in the config's Alphabet: amino acids for
+// Protein, keeping ambiguity codes for IUPAC and as AsToSeqs otherwise
+func (c ScoreConfig) Sequences(seqStrs []string) []*Sequence {
+	switch c.Alphabet {
+	case Protein:
+		return AsToProteins(seqStrs)
+	case IUPAC:
+		return AsToIUPAC(seqStrs)
+	}
+	return AsToSeqs(seqStrs)
+}
+
+// MaxScore returns the largest of scores, or Forbidden if there are none.
+// Forbidden loses to every other score, so together with AddScores it
+// reduces scores without ever computing past the sentinel.
+func MaxScore(scores ...int64) int64 {
+	best := Forbidden
+	for _, s := range scores {
+		if s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// Forbid marks the substitution of b1 with b2 (in either order) as Forbidden,
+// so an aligner gaps the bases rather than put them in one column. b1 and b2
+// must not be gaps.
+func (c *ScoreConfig) Forbid(b1, b2 Base) {
+	if int(b1) < AlphabetSize && int(b2) < AlphabetSize {
+		c.forbidden[b1][b2] = true
+		c.forbidden[b2][b1] = true
+	}
+}
+
+// GapModel selects how the gaps in a column contribute to its score.
+//
+// For example, with the default doubled scores (match 6, gap -3) the column
+// A A - - scores 6 + 4*(-3) = -6 under Pairwise, since each A is paired
+// with both gaps, but 6 + 2*(-3) = 0 under Linear and 6 + ColumnGap under
+// PerColumn. For two sequences Pairwise and Linear agree, and PerColumn
+// agrees with them when ColumnGap equals Gap.
+type GapModel int
+
+// Pairwise ... enum represents a gap model
+const (
+	// Pairwise charges Gap for every pair of a base with a gap, so the cost
+	// of a column grows quadratically with its number of gaps.
+	Pairwise GapModel = iota
+	// Linear charges Gap once per gapped sequence in a column that holds at
+	// least one base.
+	Linear
+	// PerColumn charges ColumnGap once for a column holding at least one gap
+	// and one base, however many sequences are gapped. Gap is not used.
+	PerColumn
+)
+
+// DefaultScoreConfig returns the package default scoring scheme.
+func DefaultScoreConfig() ScoreConfig {
+	return ScoreConfig{Match: match, Mismatch: mismatch, Gap: gap}
+}
+
+// EditDistanceConfig returns a scheme whose optimal global score is the
+// negated edit distance: matches score 0 and mismatches and gaps score -1.
+func EditDistanceConfig() ScoreConfig {
+	return ScoreConfig{Match: 0, Mismatch: -2, Gap: -2}
+}
+
 // SPScore returns the score for sequences
 // TODO - handle cases where sequences are of differing lengths
-func SPScore(seqs []*Sequence) (score int) {
+func SPScore(seqs []*Sequence) (score int64) {
 	for i := range seqs[0].Bases {
 		colBases := []Base{}
 		for j := range seqs {
 			colBases = append(colBases, seqs[j].Bases[i])
 		}
-		score += ColumnSPScore(colBases)
+		score = AddScores(score, ColumnSPScore(colBases))
 	}
 	return
 }
 
 // ColumnSPScore returns the sum-of-pairs score for a column of bases
-func ColumnSPScore(bases []Base) (sum int) {
+func ColumnSPScore(bases []Base) int64 {
+	return DefaultScoreConfig().ColumnSPScore(bases)
+}
+
+// PairScore returns the score of a pair of bases (or gap)
+func PairScore(b1, b2 Base) int64 {
+	return DefaultScoreConfig().PairScore(b1, b2)
+}
+
+// ColumnScore returns the sum-of-pairs score of a column of bases under cfg.
+// it is the single implementation behind every column score in the package
+// and in msa/mdp. a column of fewer than two bases holds no pairs and scores 0.
+func ColumnScore(bases []Base, cfg ScoreConfig) (sum int64) {
+	if len(bases) < 2 {
+		return 0
+	}
+	switch cfg.GapModel {
+	case Linear:
+		return cfg.linearColumnScore(bases)
+	case PerColumn:
+		return cfg.perColumnScore(bases)
+	}
 	for i, bi := range bases[:len(bases)-1] {
 		for _, bj := range bases[i+1:] {
-			sum += PairScore(bi, bj)
+			sum = AddScores(sum, cfg.PairScore(bi, bj))
 		}
 	}
 	return
 }
 
+// TerminalColumnScore is ColumnScore for a column in which terminal[i]
+// reports whether a gap in bases[i] lies before the first or after the last
+// base of its sequence. Under TerminalGapFree those gaps are left out of the
+// column, so they score nothing against bases and don't count as gaps under
+// Linear or PerColumn; otherwise it is ColumnScore.
+func TerminalColumnScore(bases []Base, terminal []bool, cfg ScoreConfig) int64 {
+	if !cfg.TerminalGapFree {
+		return ColumnScore(bases, cfg)
+	}
+	kept := make([]Base, 0, len(bases))
+	for i, b := range bases {
+		if b != X || !terminal[i] {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) < 2 {
+		return 0
+	}
+	return ColumnScore(kept, cfg)
+}
+
+// ColumnScoreFromCounts returns ColumnScore of any column holding counts[b]
+// of each base or gap b, in time depending on the number of distinct symbols
+// rather than on the depth of the column: a pair of the same symbol occurs
+// n(n-1)/2 times and a pair of two different ones n1*n2 times.
+func ColumnScoreFromCounts(counts map[Base]int, cfg ScoreConfig) (sum int64) {
+	gaps, total := counts[X], 0
+	for b1, n1 := range counts {
+		total += n1
+		for b2, n2 := range counts {
+			var pairs int64
+			switch {
+			case b1 == b2:
+				pairs = int64(n1) * int64(n1-1) / 2
+			case b1 < b2:
+				pairs = int64(n1) * int64(n2)
+			}
+			if pairs <= 0 || (cfg.GapModel != Pairwise && (b1 == X || b2 == X)) {
+				continue
+			}
+			s := cfg.PairScore(b1, b2)
+			if s == Forbidden {
+				return Forbidden
+			}
+			sum = AddScores(sum, MulScores(pairs, s))
+		}
+	}
+	switch {
+	case cfg.GapModel == Pairwise || gaps == 0 || gaps == total:
+		return
+	case cfg.GapModel == Linear:
+		return AddScores(sum, MulScores(int64(gaps), int64(cfg.Gap)))
+	default:
+		return AddScores(sum, int64(cfg.ColumnGap))
+	}
+}
+
+// ColumnPairScores breaks the score of a column down by pair: entry [i][j]
+// is the score of the pair of bases[i] and bases[j] under cfg and the
+// diagonal is 0. Under Pairwise the entries above the diagonal sum to
+// ColumnScore. Under Linear and PerColumn gaps are charged per sequence or
+// per column rather than per pair, so only pairs of two bases are filled in
+// and the gap charges are left out. It is meant for inspecting a score and
+// is not used by the solvers.
+func ColumnPairScores(bases []Base, cfg ScoreConfig) [][]int64 {
+	scores := make([][]int64, len(bases))
+	for i := range scores {
+		scores[i] = make([]int64, len(bases))
+	}
+	for i, bi := range bases {
+		for j := i + 1; j < len(bases); j++ {
+			bj := bases[j]
+			if cfg.GapModel != Pairwise && (bi == X || bj == X) {
+				continue
+			}
+			scores[i][j] = cfg.PairScore(bi, bj)
+			scores[j][i] = scores[i][j]
+		}
+	}
+	return scores
+}
+
+// ColumnSPScore returns the sum-of-pairs score for a column of bases
+func (c ScoreConfig) ColumnSPScore(bases []Base) int64 {
+	return ColumnScore(bases, c)
+}
+
+// linearColumnScore scores base pairs as usual and adds Gap once for each
+// gapped sequence
+func (c ScoreConfig) linearColumnScore(bases []Base) (sum int64) {
+	gaps := 0
+	for i, bi := range bases {
+		if bi == X {
+			gaps++
+			continue
+		}
+		for _, bj := range bases[i+1:] {
+			if bj != X {
+				sum = AddScores(sum, c.PairScore(bi, bj))
+			}
+		}
+	}
+	if gaps == len(bases) {
+		return 0
+	}
+	return AddScores(sum, MulScores(int64(gaps), int64(c.Gap)))
+}
+
+// perColumnScore scores base pairs as usual and adds ColumnGap if the column
+// holds both gaps and bases
+func (c ScoreConfig) perColumnScore(bases []Base) (sum int64) {
+	gaps := 0
+	for i, bi := range bases {
+		if bi == X {
+			gaps++
+			continue
+		}
+		for _, bj := range bases[i+1:] {
+			if bj != X {
+				sum = AddScores(sum, c.PairScore(bi, bj))
+			}
+		}
+	}
+	if gaps == 0 || gaps == len(bases) {
+		return
+	}
+	return AddScores(sum, int64(c.ColumnGap))
+}
+
 // PairScore returns the score of a pair of bases (or gap)
-func PairScore(b1, b2 Base) int {
+func (c ScoreConfig) PairScore(b1, b2 Base) int64 {
 	if b1 == X && b2 == X {
-		return 0
+		return int64(c.GapGap)
 	}
 	if (b1 == X && b2 != X) ||
 		(b2 == X && b1 != X) {
-		return gap
+		return int64(c.Gap)
+	}
+	if c.matrix != nil {
+		return int64(c.matrix.score(b1, b2))
+	}
+	if b1 == N || b2 == N {
+		return int64(c.Unknown)
+	}
+	if b1 > firstAmbiguous || b2 > firstAmbiguous {
+		// an ambiguous base matches any base of its set, see IUPACBase
+		if b1.nucleotides()&b2.nucleotides() != 0 {
+			return int64(c.Match)
+		}
+		return int64(c.Mismatch)
+	}
+	if int(b1) < AlphabetSize && int(b2) < AlphabetSize && c.forbidden[b1][b2] {
+		return Forbidden
 	}
 	if b1 != b2 {
-		return mismatch
+		return int64(c.Mismatch)
+	}
+	if int(b1) < AlphabetSize && c.hasBaseMatch[b1] {
+		return int64(c.baseMatch[b1])
 	}
-	return match
+	return int64(c.Match)
 }
@@ -1,16 +1,65 @@
 package bioinf
 
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
 // Sequence represents a nucleotide base sequence
 type Sequence struct {
 	Bases []Base
 }
 
+// RNAString is String with T written as U, for sequences read as RNA
+func (s *Sequence) RNAString() string {
+	return strings.ReplaceAll(s.String(), "T", "U")
+}
+
+// AppendBase appends b to s
+func (s *Sequence) AppendBase(b Base) {
+	s.Bases = append(s.Bases, b)
+}
+
+// Len returns the number of bases in s, gaps included
+func (s *Sequence) Len() int {
+	return len(s.Bases)
+}
+
+// SequenceFromString is a strict AToSeq: it converts seq with ParseBase and
+// returns an error naming the position of the first rune ParseBase rejects.
+// whitespace is skipped as in AToSeq.
+func SequenceFromString(seq string) (*Sequence, error) {
+	s := NewSequence()
+	for i, r := range []rune(seq) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b, err := ParseBase(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w at position %d", err, i)
+		}
+		s.AppendBase(b)
+	}
+	return s, nil
+}
+
+// String returns the bases of s as their one-letter codes, see Base.String
+func (s *Sequence) String() string {
+	var sb strings.Builder
+	for _, b := range s.Bases {
+		sb.WriteString(b.String())
+	}
+	return sb.String()
+}
+
 // NewSequence is a Sequence constructor
 func NewSequence() *Sequence {
 	return &Sequence{Bases: []Base{}}
 }
 
-// Base represents a nucleotide base
+// Base represents a nucleotide base, or an amino acid residue, see Residue
 type Base int
 
 // A ... enum represents a nucleotide
@@ -20,8 +69,210 @@ const (
 	G
 	T
 	X // represents a gap "-"
+	N // an unknown or ambiguous residue, not a gap
+)
+
+// AminoAcids holds the one-letter codes of the twenty standard amino acids in
+// the order of their residues, see Residue
+const AminoAcids = "ACDEFGHIKLMNPQRSTVWY"
+
+// firstResidue is the Base of the first amino acid. residues follow the
+// nucleotides, the gap and N, so the two alphabets never share a value and a
+// protein residue is never mistaken for a nucleotide of the same letter.
+const firstResidue = N + 1
+
+// Residue returns the Base of the amino acid with one-letter code r, case
+// ignored, and N for any other letter, such as the unknown residue X or the
+// ambiguity codes B and Z.
+func Residue(r rune) Base {
+	if i := strings.IndexRune(AminoAcids, unicode.ToUpper(r)); i >= 0 {
+		return firstResidue + Base(i)
+	}
+	return N
+}
+
+// firstAmbiguous precedes the ambiguous nucleotides, which follow the
+// residues. each stands for a set of two or more nucleotides and is
+// firstAmbiguous plus the bit mask of the set, with A 1, C 2, G 4 and T 8.
+const firstAmbiguous = firstResidue + Base(len(AminoAcids))
+
+// iupacCodes maps each IUPAC nucleotide code to the mask of the bases it
+// stands for
+var iupacCodes = map[rune]int{
+	'A': 1, 'C': 2, 'G': 4, 'T': 8,
+	'M': 3, 'R': 5, 'W': 9, 'S': 6, 'Y': 10, 'K': 12,
+	'V': 7, 'H': 11, 'D': 13, 'B': 14, 'N': 15,
+}
+
+// IUPACBase returns the Base of the IUPAC nucleotide code r, case ignored:
+// A, C, G or T for a concrete base and an ambiguous base for any other code,
+// including N, which stands for every nucleotide rather than for an unknown
+// residue. an unrecognized letter is N.
+func IUPACBase(r rune) Base {
+	mask, ok := iupacCodes[unicode.ToUpper(r)]
+	switch {
+	case !ok:
+		return N
+	case mask&(mask-1) == 0:
+		return Base(bits.TrailingZeros(uint(mask)))
+	}
+	return firstAmbiguous + Base(mask)
+}
+
+// nucleotides returns the mask of the nucleotides b stands for, 0 for a gap,
+// N or a residue
+func (b Base) nucleotides() int {
+	switch {
+	case b >= A && b <= T:
+		return 1 << b
+	case b > firstAmbiguous && b <= firstAmbiguous+15:
+		return int(b - firstAmbiguous)
+	}
+	return 0
+}
+
+// Nucleotides returns the concrete bases b stands for, in order: b itself
+// for A, C, G or T, the set of an ambiguous base, and none for the gap X, N
+// or a residue.
+func (b Base) Nucleotides() (bases []Base) {
+	for _, c := range ConcreteBases() {
+		if b.nucleotides()&(1<<c) != 0 {
+			bases = append(bases, c)
+		}
+	}
+	return
+}
+
+// AmbiguityCode returns the base standing for exactly the nucleotides of
+// bases together, see Nucleotides: a concrete base for one, the ambiguous
+// base of the IUPAC code for two or more, and the gap X for none.
+func AmbiguityCode(bases ...Base) Base {
+	mask := 0
+	for _, b := range bases {
+		mask |= b.nucleotides()
+	}
+	switch {
+	case mask == 0:
+		return X
+	case mask&(mask-1) == 0:
+		return Base(bits.TrailingZeros(uint(mask)))
+	}
+	return firstAmbiguous + Base(mask)
+}
+
+// String returns the one-letter code of b: the nucleotide, "-" for the gap
+// X, "N", the amino acid code of a residue or the IUPAC code of an
+// ambiguous base
+func (b Base) String() string {
+	switch {
+	case b >= A && b <= T:
+		return string("ACGT"[b])
+	case b == X:
+		return "-"
+	case b == N:
+		return "N"
+	case b >= firstResidue && b < firstAmbiguous:
+		return string(AminoAcids[b-firstResidue])
+	}
+	for code, mask := range iupacCodes {
+		if mask&(mask-1) != 0 && b == firstAmbiguous+Base(mask) {
+			return string(code)
+		}
+	}
+	return fmt.Sprintf("Base(%d)", int(b))
+}
+
+// ParseBase is a strict AToBase for a single rune: it accepts A, C, G, T, U
+// and N in either case and the GapChars, and returns an error for anything else
+// rather than converting it to N.
+func ParseBase(r rune) (Base, error) {
+	if IsGap(r) {
+		return X, nil
+	}
+	switch unicode.ToUpper(r) {
+	case 'A':
+		return A, nil
+	case 'C':
+		return C, nil
+	case 'G':
+		return G, nil
+	case 'T', 'U':
+		return T, nil
+	case 'N':
+		return N, nil
+	}
+	return 0, fmt.Errorf("bioinf: invalid base %q", r)
+}
+
+// AlphabetSize is the number of concrete bases, A through T
+const AlphabetSize = int(T) + 1
+
+// ConcreteBases returns the concrete bases in order, without the gap X or
+// the unknown N. they are 0 through AlphabetSize-1, so they can index
+// frequency vectors and scoring matrices directly.
+func ConcreteBases() []Base {
+	return []Base{A, C, G, T}
+}
+
+// Complement returns the Watson-Crick partner of b. an ambiguous base maps to
+// the code for the partners of its set, e.g. R (A or G) to Y (C or T); the
+// gap X, N and residues have no partner and map to themselves.
+func (b Base) Complement() Base {
+	switch {
+	case b >= A && b <= T:
+		return T - b
+	case b.nucleotides() != 0:
+		// reversing the four mask bits swaps A with T and C with G
+		return firstAmbiguous + Base(bits.Reverse8(uint8(b.nucleotides()))>>4)
+	}
+	return b
+}
+
+// ReverseComplement returns the reverse complement of s as a new Sequence
+func (s *Sequence) ReverseComplement() *Sequence {
+	rc := &Sequence{Bases: make([]Base, len(s.Bases))}
+	for i, b := range s.Bases {
+		rc.Bases[len(s.Bases)-1-i] = b.Complement()
+	}
+	return rc
+}
+
+// Alphabet identifies the set of bases a Sequence may hold
+type Alphabet int
+
+// DNA ... enum represents an alphabet
+const (
+	DNA     Alphabet = iota // A, C, G, T
+	Protein                 // the twenty AminoAcids
+	IUPAC                   // A, C, G, T and the ambiguous nucleotides
+	RNA                     // A, C, G, U, held as T
 )
 
+// Contains reports whether b belongs to the alphabet. the gap X belongs to
+// every alphabet.
+func (alpha Alphabet) Contains(b Base) bool {
+	switch alpha {
+	case DNA, RNA:
+		return b >= A && b <= N
+	case IUPAC:
+		return (b >= A && b <= N) || b.nucleotides() != 0
+	case Protein:
+		return b == X || b == N || (b >= firstResidue && b < firstResidue+Base(len(AminoAcids)))
+	default:
+		return false
+	}
+}
+
+// Valid returns an error describing the first base of s that is outside alpha
+func (s *Sequence) Valid(alpha Alphabet) error {
+	for i, b := range s.Bases {
+		if !alpha.Contains(b) {
+			return fmt.Errorf("bioinf: invalid base %d at position %d", b, i)
+		}
+	}
+	return nil
+}
+
 // AsToSeqs converts strings to Sequences
 func AsToSeqs(seqStrs []string) (seqs []*Sequence) {
 	for _, seqStr := range seqStrs {
@@ -30,27 +281,111 @@ func AsToSeqs(seqStrs []string) (seqs []*Sequence) {
 	return
 }
 
-// AToSeq converts string to Sequence
+// AToSeq converts string to Sequence. whitespace, such as the newlines of
+// wrapped sequence lines, is skipped.
 func AToSeq(seq string) *Sequence {
 	s := NewSequence()
 	for _, b := range seq {
+		if unicode.IsSpace(b) {
+			continue
+		}
 		s.Bases = append(s.Bases, AToBase(string(b)))
 	}
 	return s
 }
 
-// AToBase converts string to Base
+// AsToProteins converts strings of amino acid codes to Sequences of residues
+func AsToProteins(seqStrs []string) (seqs []*Sequence) {
+	for _, seqStr := range seqStrs {
+		seqs = append(seqs, AToProtein(seqStr))
+	}
+	return
+}
+
+// AToProtein converts a string of amino acid codes to a Sequence of
+// residues. whitespace, gap characters and lower case are handled as in
+// AToSeq.
+func AToProtein(seq string) *Sequence {
+	s := NewSequence()
+	for _, r := range seq {
+		switch {
+		case unicode.IsSpace(r):
+		case IsGap(r):
+			s.Bases = append(s.Bases, X)
+		case MaskLowerCase && unicode.IsLower(r):
+			s.Bases = append(s.Bases, N)
+		default:
+			s.Bases = append(s.Bases, Residue(r))
+		}
+	}
+	return s
+}
+
+// AsToIUPAC converts strings of IUPAC nucleotide codes to Sequences, see
+// AToIUPAC
+func AsToIUPAC(seqStrs []string) (seqs []*Sequence) {
+	for _, seqStr := range seqStrs {
+		seqs = append(seqs, AToIUPAC(seqStr))
+	}
+	return
+}
+
+// AToIUPAC is like AToSeq but keeps ambiguity codes, see IUPACBase, so they
+// match the nucleotides they stand for rather than scoring Unknown. the
+// ambiguous bases are understood by the scoring, display and consensus but
+// not by the encoding helpers of package msa.
+func AToIUPAC(seq string) *Sequence {
+	s := NewSequence()
+	for _, r := range seq {
+		switch {
+		case unicode.IsSpace(r):
+		case IsGap(r):
+			s.Bases = append(s.Bases, X)
+		case MaskLowerCase && unicode.IsLower(r):
+			s.Bases = append(s.Bases, N)
+		default:
+			s.Bases = append(s.Bases, IUPACBase(r))
+		}
+	}
+	return s
+}
+
+// GapChars holds the characters that denote a gap. '.' is included for
+// alignments using the HMMER convention of '.' for gaps in insert columns.
+// every gap character converts to X, so which one was used is not preserved.
+var GapChars = "-."
+
+// IsGap reports whether r is one of GapChars
+func IsGap(r rune) bool {
+	return strings.ContainsRune(GapChars, r)
+}
+
+// MaskLowerCase makes the parsers treat soft-masked (lower case) residues
+// as unknown, so they convert to N and score Unknown, rather than folding
+// them to upper case like the default.
+var MaskLowerCase = false
+
+// AToBase converts string to Base. case is ignored unless MaskLowerCase is
+// set, so soft-masked (lower case) bases score like unmasked ones. gap
+// characters become X, the uracil U of RNA becomes T and anything else
+// unrecognized, such as N or an IUPAC ambiguity code, becomes N.
 func AToBase(b string) Base {
-	switch b {
+	if len(b) == 1 && IsGap(rune(b[0])) {
+		return X
+	}
+	if MaskLowerCase && b != strings.ToUpper(b) {
+		return N
+	}
+	switch strings.ToUpper(b) {
 	case "A":
 		return A
 	case "C":
 		return C
 	case "G":
 		return G
-	case "T":
+	case "T", "U":
 		return T
 	default:
-		return X
+		return N
 	}
 }
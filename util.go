@@ -2,9 +2,11 @@ package bioinf
 
 import "math"
 
-func Slice2D(rows, cols, defVal int) [][]int {
-	m := make([][]int, rows)
-	x := make([]int, rows*cols)
+// Slice2D returns a rows by cols matrix backed by one slice, every entry
+// set to defVal
+func Slice2D[T any](rows, cols int, defVal T) [][]T {
+	m := make([][]T, rows)
+	x := make([]T, rows*cols)
 	for j := range x {
 		x[j] = defVal
 	}
@@ -15,7 +17,7 @@ func Slice2D(rows, cols, defVal int) [][]int {
 }
 
 func Max(ints ...int) int {
-	max := math.MinInt64
+	max := math.MinInt
 	for _, x := range ints {
 		if x > max {
 			max = x